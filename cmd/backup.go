@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/backup"
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/output"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog"
+)
+
+// NewCmdBackup returns "certadm backup" command.
+func NewCmdBackup() *cobra.Command {
+	var backupDir string
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Manage the backups taken by certadm",
+	}
+
+	cmd.PersistentFlags().StringVar(&backupDir, "backup-dir", constants.DefaultBackupDir, "The directory to save the backups.")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the backups",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := printBackups(os.Stdout, backupDir); err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+		},
+	})
+	cmd.AddCommand(newCmdBackupShow(&backupDir))
+	cmd.AddCommand(newCmdBackupPrune(&backupDir))
+	return cmd
+}
+
+func newCmdBackupShow(backupDir *string) *cobra.Command {
+	var outputFormat string
+	cmd := &cobra.Command{
+		Use:   "show <backup-name>",
+		Short: "Show the manifest of the backup",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := showBackup(os.Stdout, *backupDir, args[0], outputFormat); err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", output.YAMLOutput, "Output format. One of: json|yaml.")
+	return cmd
+}
+
+func newCmdBackupPrune(backupDir *string) *cobra.Command {
+	var keep int
+	var maxAge time.Duration
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the old backups by count or age",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if keep <= 0 && maxAge <= 0 {
+				klog.Error("please specify '--keep' or '--max-age'")
+				os.Exit(1)
+			}
+
+			removed, err := backup.Prune(*backupDir, keep, maxAge)
+			for _, b := range removed {
+				fmt.Printf("[backup] Removed backup %s\n", b.Name)
+			}
+			if err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().IntVar(&keep, "keep", 0, "The number of the latest backups to keep.")
+	cmd.Flags().DurationVar(&maxAge, "max-age", 0, "Remove the backups older than the duration, e.g. 720h.")
+	return cmd
+}
+
+func printBackups(out io.Writer, backupDir string) error {
+	backups, err := backup.List(backupDir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCREATED\tHOSTNAME\tCERTADM\tKUBEADM\tFILES\tPATH")
+	for _, b := range backups {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			b.Name,
+			b.CreatedAt.Format("Jan 02, 2006 15:04 MST"),
+			b.Manifest.Hostname,
+			b.Manifest.CertadmVersion,
+			b.Manifest.KubeadmVersion,
+			len(b.Manifest.Files),
+			b.Path,
+		)
+	}
+	return w.Flush()
+}
+
+func showBackup(out io.Writer, backupDir, name, format string) error {
+	b, err := backup.Get(backupDir, name)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch format {
+	case output.JSONOutput:
+		data, err = json.MarshalIndent(b.Manifest, "", "    ")
+		data = append(data, '\n')
+	case output.YAMLOutput:
+		data, err = yaml.Marshal(b.Manifest)
+	default:
+		return errors.Errorf("unsupported output format %q, supported formats: %s|%s", format, output.JSONOutput, output.YAMLOutput)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pytimer/certadm/pkg/backup"
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/kubeconfig"
+	"github.com/pytimer/certadm/pkg/renewal"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+// NewCmdCA returns "certadm ca" command.
+func NewCmdCA() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ca",
+		Short: "Manage the Kubernetes cluster certificate authorities",
+	}
+
+	cmd.AddCommand(NewCmdCARegenerate())
+	cmd.AddCommand(NewCmdCARotate())
+	return cmd
+}
+
+// NewCmdCARegenerate returns "certadm ca regenerate" command, it is an alias of "certadm ca rotate" for the CAs
+// which can be regenerated.
+func NewCmdCARegenerate() *cobra.Command {
+	opts := &caRotateOptions{skipKubectl: true}
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("regenerate [%s]", strings.Join(certs.RegenerableCAs, "|")),
+		Short: "Regenerate the CA and re-issue the certificates signed by it",
+		Long: "Regenerate the CA and re-issue the certificates signed by it, the same as 'certadm ca rotate' of the CA. The old " +
+			"CA is kept in the trust bundle, so the aggregated API servers keep trusting the front proxy until they load the new CA.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if !sets.NewString(certs.RegenerableCAs...).Has(args[0]) {
+				klog.Errorf("the %s CA can't be regenerated, supported CAs: %s", args[0], strings.Join(certs.RegenerableCAs, "|"))
+				os.Exit(1)
+			}
+
+			var err error
+			opts.criSocketPath, err = DetectCRISocket(nil)
+			if err != nil {
+				klog.Warningf("[ca] failed to detected and using CRI socket: %v", err)
+				opts.criSocketPath = constants.DefaultDockerCRISocket
+			}
+
+			if err := opts.run(args); err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.kubernetesDir, "root-dir", constants.KubernetesDir, "The path save the Kubernetes certificates and kubeconfig.")
+	cmd.Flags().StringVar(&opts.backupDir, "backup-dir", constants.DefaultBackupDir, "The directory to save the backups.")
+	cmd.Flags().StringVar(&opts.configMapOutput, "configmap-output", "", fmt.Sprintf("Write the %s/%s ConfigMap manifest with the front proxy CA trust bundle to the file.", constants.NamespaceSystem, constants.ExtensionAPIServerAuthenticationConfigMap))
+
+	return cmd
+}
+
+type caRotateOptions struct {
+	kubernetesDir   string
+	backupDir       string
+	configMapOutput string
+	criSocketPath   string
+	finalize        bool
+	kubectlConfig   string
+	skipKubectl     bool
+}
+
+// NewCmdCARotate returns "certadm ca rotate" command.
+func NewCmdCARotate() *cobra.Command {
+	opts := &caRotateOptions{}
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("rotate [%s]...", strings.Join(certs.RotatableCAs, "|")),
+		Short: "Rotate the CAs with an overlapping trust bundle and re-issue the certificates signed by them",
+		Long: "Rotate the CAs with an overlapping trust bundle and re-issue the certificates signed by them. All the CAs are rotated " +
+			"if no CA is given. The CA certificate file becomes the trust bundle of the new and the old CA certificates, so the " +
+			"certificates signed by the old CA are still trusted. Run 'certadm ca rotate --finalize' to drop the old CA " +
+			"certificates from the trust bundle once all the nodes trust the new CA.",
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			opts.criSocketPath, err = DetectCRISocket(nil)
+			if err != nil {
+				klog.Warningf("[ca] failed to detected and using CRI socket: %v", err)
+				opts.criSocketPath = constants.DefaultDockerCRISocket
+			}
+
+			if err := opts.run(args); err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.kubernetesDir, "root-dir", constants.KubernetesDir, "The path save the Kubernetes certificates and kubeconfig.")
+	cmd.Flags().StringVar(&opts.backupDir, "backup-dir", constants.DefaultBackupDir, "The directory to save the backups.")
+	cmd.Flags().StringVar(&opts.configMapOutput, "configmap-output", "", fmt.Sprintf("Write the %s/%s ConfigMap manifest with the front proxy CA trust bundle to the file.", constants.NamespaceSystem, constants.ExtensionAPIServerAuthenticationConfigMap))
+	cmd.Flags().BoolVar(&opts.finalize, "finalize", false, "Drop the old CA certificates from the trust bundles, run it once all the nodes trust the new CAs.")
+	addKubectlConfigFlags(cmd.Flags(), &opts.kubectlConfig, &opts.skipKubectl)
+
+	return cmd
+}
+
+func (o *caRotateOptions) run(caNames []string) error {
+	certificatesDir := filepath.Join(o.kubernetesDir, "pki")
+	caNames, err := o.rotatableCAs(certificatesDir, caNames)
+	if err != nil {
+		return err
+	}
+
+	b, err := backup.Create(o.backupDir, o.kubernetesDir, constants.KubeletCertificatesPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[ca] Backup old Kubernetes certificates and kubeconfig to %s, use 'certadm rollback %s' to restore them\n", b.Path, b.Name)
+
+	rotate := o.rotate
+	if o.finalize {
+		rotate = o.finalizeRotation
+	}
+	changed, err := rotate(certificatesDir, caNames)
+	if err != nil {
+		fmt.Printf("[ca] Failed to rotate the CAs, restore Kubernetes certificates and kubeconfig from %s\n", b.Path)
+		if restoreErr := backup.Restore(b, o.kubernetesDir, constants.KubeletCertificatesPath); restoreErr != nil {
+			return errors.Wrapf(restoreErr, "rotate failed: %v; restoring backup %s also failed", err, b.Name)
+		}
+		return err
+	}
+	if !changed {
+		fmt.Println("[ca] No CA rotation is in progress, nothing to finalize")
+		return nil
+	}
+
+	restartControlPlane(o.kubernetesDir, o.criSocketPath)
+
+	if !o.finalize {
+		fmt.Printf("[ca] The CA certificate files are the trust bundles of the new and the old CAs now, copy them to the other nodes, " +
+			"and run 'certadm ca rotate --finalize' once all the nodes trust the new CAs\n")
+	}
+	return nil
+}
+
+// rotatableCAs returns the CAs given in the command line, or all the existing CAs which can be rotated.
+func (o *caRotateOptions) rotatableCAs(certificatesDir string, caNames []string) ([]string, error) {
+	if len(caNames) > 0 {
+		for _, caName := range caNames {
+			if !sets.NewString(certs.RotatableCAs...).Has(caName) {
+				return nil, errors.Errorf("the %s CA can't be rotated, supported CAs: %s", caName, strings.Join(certs.RotatableCAs, "|"))
+			}
+		}
+		return caNames, nil
+	}
+
+	for _, caName := range certs.RotatableCAs {
+		if exists, err := path.Exists(path.CheckFollowSymlink, filepath.Join(certificatesDir, caName+".crt")); err != nil {
+			return nil, err
+		} else if !exists {
+			klog.Warningf("[ca] the %s CA not exists, skip it", caName)
+			continue
+		}
+		caNames = append(caNames, caName)
+	}
+	return caNames, nil
+}
+
+// rotate rotates the CAs and re-issues the certificates and kubeconfig files signed by them.
+func (o *caRotateOptions) rotate(certificatesDir string, caNames []string) (bool, error) {
+	renewed := []string{}
+	for _, caName := range caNames {
+		fmt.Printf("[ca] Rotate the %s CA and re-issue the certificates signed by it\n", caName)
+		bundle, err := certs.RotateCA(certificatesDir, caName)
+		if err != nil {
+			return false, err
+		}
+		renewed = append(renewed, certs.SignedCertificates(caName)...)
+
+		switch caName {
+		case constants.CACertAndKeyBaseName:
+			fmt.Println("[ca] Re-issue the kubeconfig files and trust the CA bundle")
+			if err := kubeconfig.RenewKubeConfigFiles(o.kubernetesDir, certificatesDir, nil); err != nil {
+				return false, err
+			}
+			if err := o.updateKubeConfigs(bundle); err != nil {
+				return false, err
+			}
+			renewed = append(renewed, kubeconfig.KubeConfigFiles...)
+
+			if err := renewKubeletCertificates(o.kubernetesDir, nil); err != nil {
+				return false, err
+			}
+			if err := renewCASignedKubeletServingCertificate(certificatesDir); err != nil {
+				return false, err
+			}
+		case constants.FrontProxyCACertAndKeyBaseName:
+			if err := o.writeConfigMap(bundle); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return true, renewal.Validate(o.kubernetesDir, renewed)
+}
+
+// renewCASignedKubeletServingCertificate re-issues the kubelet serving certificate with the new cluster CA if it is
+// signed by the cluster CA, the self-signed one and the one rotated by the kubelet are untouched.
+func renewCASignedKubeletServingCertificate(certificatesDir string) error {
+	caSigned, err := certs.KubeletServingCertificateCASigned(constants.KubeletCertificatesPath, certificatesDir)
+	if err != nil || !caSigned {
+		return err
+	}
+	fmt.Println("[ca] Re-issue the kubelet serving certificate signed by the cluster CA")
+	return certs.RenewKubeletServingCertificate(constants.KubeletCertificatesPath, certificatesDir, &certs.KubeletServingOptions{
+		Mode: certs.KubeletServingCASigned,
+	})
+}
+
+// finalizeRotation drops the old CA certificates from the trust bundles of the CAs, it returns false if
+// no CA rotation is in progress.
+func (o *caRotateOptions) finalizeRotation(certificatesDir string, caNames []string) (bool, error) {
+	changed := false
+	for _, caName := range caNames {
+		caCert, err := certs.FinalizeCARotation(certificatesDir, caName)
+		if err != nil {
+			return false, err
+		}
+		if caCert == nil {
+			fmt.Printf("[ca] The rotation of the %s CA is not in progress, skip it\n", caName)
+			continue
+		}
+		fmt.Printf("[ca] Dropped the old CA certificates from the %s trust bundle\n", caName)
+		changed = true
+
+		switch caName {
+		case constants.CACertAndKeyBaseName:
+			if err := o.updateKubeConfigs([]*x509.Certificate{caCert}); err != nil {
+				return false, err
+			}
+		case constants.FrontProxyCACertAndKeyBaseName:
+			if err := o.writeConfigMap([]*x509.Certificate{caCert}); err != nil {
+				return false, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// updateKubeConfigs embeds the CA certificates in the kubeconfig files and merges the admin credentials
+// into the kubectl kubeconfig file.
+func (o *caRotateOptions) updateKubeConfigs(caCerts []*x509.Certificate) error {
+	if err := kubeconfig.SetCertificateAuthorities(o.kubernetesDir, caCerts); err != nil {
+		return err
+	}
+	if o.skipKubectl {
+		return nil
+	}
+	fmt.Printf("[ca] Merge the admin credentials of admin.conf into %s\n", o.kubectlConfig)
+	return mergeKubectlConfig(o.kubernetesDir, o.kubectlConfig)
+}
+
+func (o *caRotateOptions) writeConfigMap(caCerts []*x509.Certificate) error {
+	if o.configMapOutput == "" {
+		klog.Warningf("[ca] the aggregated API servers must trust the %s CA bundle, please restart them after the kube-apiserver updates the %s ConfigMap", constants.FrontProxyCACertAndKeyBaseName, constants.ExtensionAPIServerAuthenticationConfigMap)
+		return nil
+	}
+	fmt.Printf("[ca] Write the %s ConfigMap to %s\n", constants.ExtensionAPIServerAuthenticationConfigMap, o.configMapOutput)
+	return certs.WriteRequestHeaderCAConfigMap(o.configMapOutput, caCerts...)
+}
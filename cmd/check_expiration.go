@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/kubeconfig"
+	"github.com/pytimer/certadm/pkg/output"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+)
+
+type checkExpirationOptions struct {
+	kubernetesDir string
+	outputFormat  string
+}
+
+// NewCmdCheckExpiration returns "certadm check-expiration" command.
+func NewCmdCheckExpiration() *cobra.Command {
+	opts := &checkExpirationOptions{}
+	cmd := &cobra.Command{
+		Use:   "check-expiration",
+		Short: "Check the expiration of the Kubernetes cluster certificates and kubeconfig",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := output.ValidateFormat(opts.outputFormat); err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+
+			if err := opts.run(os.Stdout); err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.kubernetesDir, "root-dir", constants.KubernetesDir, "The path save the Kubernetes certificates and kubeconfig.")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", output.TableOutput, "Output format. One of: table|json|yaml.")
+
+	return cmd
+}
+
+func (o *checkExpirationOptions) run(out io.Writer) error {
+	certificatesDir := filepath.Join(o.kubernetesDir, "pki")
+
+	infos, err := certs.ListCertificates(certificatesDir)
+	if err != nil {
+		return err
+	}
+
+	kubeconfigInfos, err := kubeconfig.ListCertificates(o.kubernetesDir)
+	if err != nil {
+		return err
+	}
+	infos = append(infos, kubeconfigInfos...)
+
+	kubeletServingInfo, err := certs.KubeletServingCertificateInfo(constants.KubeletCertificatesPath)
+	if err != nil {
+		return err
+	}
+	if kubeletServingInfo != nil {
+		infos = append(infos, kubeletServingInfo)
+	}
+
+	return output.PrintCertificates(out, o.outputFormat, infos)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pytimer/certadm/pkg/config"
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/kubeadm"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+)
+
+// NewCmdConfig returns "certadm config" command.
+func NewCmdConfig() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the kubeadm configuration used by certadm",
+	}
+
+	cmd.AddCommand(NewCmdConfigGenerate())
+	return cmd
+}
+
+type configGenerateOptions struct {
+	kubernetesDir string
+	configMapFile string
+	apiVersion    string
+	outputFile    string
+}
+
+// NewCmdConfigGenerate returns "certadm config generate" command.
+func NewCmdConfigGenerate() *cobra.Command {
+	opts := &configGenerateOptions{}
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate the kubeadm config file from the existing certificates and kubeconfig",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := opts.run(); err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.kubernetesDir, "root-dir", constants.KubernetesDir, "The path save the Kubernetes certificates and kubeconfig.")
+	cmd.Flags().StringVar(&opts.configMapFile, "config-from-configmap", "", "Load the cluster configuration from the kube-system/kubeadm-config ConfigMap dumped by 'kubectl get configmap -o yaml'.")
+	cmd.Flags().StringVar(&opts.apiVersion, "api-version", "", "The kubeadm config API version, e.g. v1beta2. Defaults to the API version of the installed kubeadm.")
+	cmd.Flags().StringVar(&opts.outputFile, "output-file", "", "Write the kubeadm config to the file instead of stdout.")
+
+	return cmd
+}
+
+func (o *configGenerateOptions) run() error {
+	criSocketPath, err := DetectCRISocket(nil)
+	if err != nil {
+		klog.Warningf("[config] failed to detected and using CRI socket: %v", err)
+		criSocketPath = ""
+	}
+
+	c, err := reconstructConfig(o.kubernetesDir, criSocketPath, o.configMapFile)
+	if err != nil {
+		return err
+	}
+	b, err := kubeadm.MarshalConfig(c, o.apiVersion)
+	if err != nil {
+		return err
+	}
+
+	if o.outputFile == "" {
+		fmt.Print(string(b))
+		return nil
+	}
+	if err := ioutil.WriteFile(o.outputFile, b, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("[config] Wrote the kubeadm config to %s\n", o.outputFile)
+	return nil
+}
+
+// reconstructConfig reconstructs the kubeadm configuration from the existing certificates, kubeconfig files and
+// static pod manifests, the cluster configuration in the kubeadm-config ConfigMap takes precedence if the
+// configMapFile is set. The Kubernetes version defaults to the installed kubeadm version, so kubeadm doesn't
+// fetch it from the internet.
+func reconstructConfig(kubernetesDir, criSocketPath, configMapFile string) (*kubeadm.Config, error) {
+	c, err := config.FromCluster(kubernetesDir)
+	if err != nil {
+		return nil, err
+	}
+	if configMapFile != "" {
+		cm, err := config.FromConfigMapFile(configMapFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Merge(cm, c)
+		c = cm
+	}
+
+	if c.CRISocket == "" {
+		c.CRISocket = criSocketPath
+	}
+	if c.KubernetesVersion == "" {
+		if v, err := kubeadm.GetKubeadmVersion(); err != nil {
+			klog.Warningf("[config] failed to get the kubeadm version, the kubernetesVersion is not set: %v", err)
+		} else {
+			c.KubernetesVersion = v
+		}
+	}
+	return c, nil
+}
+
+// writeTempConfigFile writes the kubeadm configuration to a temporary file, the caller should remove the file.
+func writeTempConfigFile(c *kubeadm.Config) (string, error) {
+	b, err := kubeadm.MarshalConfig(c, "")
+	if err != nil {
+		return "", err
+	}
+
+	f, err := ioutil.TempFile("", "certadm-kubeadm-config-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(b); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pytimer/certadm/pkg/config"
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/kubeconfig"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+)
+
+// NewCmdKubeConfig returns "certadm kubeconfig" command.
+func NewCmdKubeConfig() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "Manage the kubeconfig files signed by the cluster CA",
+	}
+
+	cmd.AddCommand(NewCmdKubeConfigUser())
+	return cmd
+}
+
+type kubeconfigUserOptions struct {
+	kubernetesDir string
+	name          string
+	orgs          []string
+	validity      string
+	server        string
+	output        string
+}
+
+// NewCmdKubeConfigUser returns "certadm kubeconfig user" command.
+func NewCmdKubeConfigUser() *cobra.Command {
+	opts := &kubeconfigUserOptions{}
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Create a kubeconfig of a user signed by the cluster CA",
+		Long: "Create a kubeconfig of a user signed by the cluster CA, e.g. for the break-glass users and the CI robots. The " +
+			"kubeconfig embeds a new private key, the client certificate of the user and the CA trust bundle, and is written to " +
+			"stdout unless '--output' is set. The client certificate can't be revoked, so keep its validity short.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := opts.run(); err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.kubernetesDir, "root-dir", constants.KubernetesDir, "The path save the Kubernetes certificates and kubeconfig.")
+	cmd.Flags().StringVar(&opts.name, "name", "", "The user name, which is the common name of the client certificate.")
+	cmd.Flags().StringSliceVar(&opts.orgs, "org", nil, "The group of the user, which is an organization of the client certificate. The flag can be repeated.")
+	cmd.Flags().StringVar(&opts.validity, "validity", "24h", "The validity period of the client certificate, e.g. 8h, 7d or 1y. It is capped at the expiration of the CA.")
+	cmd.Flags().StringVar(&opts.server, "server", "", "The API server URL, defaults to the server of admin.conf.")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "The file to write the kubeconfig to, defaults to stdout.")
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+func (o *kubeconfigUserOptions) run() error {
+	validity, err := config.ParseValidity(o.validity)
+	if err != nil {
+		return err
+	}
+	if sets.NewString(o.orgs...).Has(constants.SystemPrivilegedGroup) {
+		klog.Warningf("[kubeconfig] the user %s is in the %s group, which bypasses the RBAC authorization", o.name, constants.SystemPrivilegedGroup)
+	}
+
+	c, err := kubeconfig.CreateUserKubeConfig(o.kubernetesDir, filepath.Join(o.kubernetesDir, "pki"), &kubeconfig.UserOptions{
+		Name:          o.name,
+		Organizations: o.orgs,
+		Validity:      validity,
+		Server:        o.server,
+	})
+	if err != nil {
+		return err
+	}
+
+	if o.output != "" {
+		if err := kubeconfig.WriteToFile(c, o.output); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "[kubeconfig] Wrote the kubeconfig of the user %s to %s\n", o.name, o.output)
+		return nil
+	}
+	b, err := kubeconfig.Encode(c)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
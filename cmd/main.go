@@ -6,6 +6,11 @@ import (
 	"os"
 
 	_ "github.com/pytimer/certadm/pkg/kubeadm/v1alpha2"
+	_ "github.com/pytimer/certadm/pkg/kubeadm/v1alpha3"
+	_ "github.com/pytimer/certadm/pkg/kubeadm/v1beta1"
+	_ "github.com/pytimer/certadm/pkg/kubeadm/v1beta2"
+	_ "github.com/pytimer/certadm/pkg/kubeadm/v1beta3"
+	_ "github.com/pytimer/certadm/pkg/kubeadm/v1beta4"
 
 	"github.com/lithammer/dedent"
 	"github.com/spf13/cobra"
@@ -61,6 +66,13 @@ func main() {
 
 	cmds.ResetFlags()
 	cmds.AddCommand(NewCmdRenew())
+	cmds.AddCommand(NewCmdCheckExpiration())
+	cmds.AddCommand(NewCmdCA())
+	cmds.AddCommand(NewCmdSA())
+	cmds.AddCommand(NewCmdKubeConfig())
+	cmds.AddCommand(NewCmdRollback())
+	cmds.AddCommand(NewCmdBackup())
+	cmds.AddCommand(NewCmdConfig())
 
 	if err := cmds.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
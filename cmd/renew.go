@@ -2,45 +2,87 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/pytimer/certadm/pkg/backup"
 	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/config"
+	"github.com/pytimer/certadm/pkg/config/v1alpha1"
 	"github.com/pytimer/certadm/pkg/constants"
 	"github.com/pytimer/certadm/pkg/kubeadm"
 	"github.com/pytimer/certadm/pkg/kubeconfig"
+	"github.com/pytimer/certadm/pkg/renewal"
 	"github.com/pytimer/certadm/pkg/util"
 	utilruntime "github.com/pytimer/certadm/pkg/util/runtime"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"k8s.io/klog"
-	"k8s.io/kubernetes/pkg/util/initsystem"
 	utilsexec "k8s.io/utils/exec"
+	"k8s.io/utils/path"
 )
 
+// reconstructedConfigFile is the config file shown in the kubeadm commands of a dry run with the reconstructed kubeadm
+// config, which is kept in memory instead of being written to a temporary file.
+const reconstructedConfigFile = "<reconstructed-kubeadm-config>"
+
 type renewOptions struct {
-	kubernetesDir string
-	configFile    string
-	criSocketPath string
+	kubernetesDir  string
+	backupDir      string
+	configFile     string
+	configMapFile  string
+	criSocketPath  string
+	backend        string
+	rotateKeys     bool
+	dryRun         bool
+	addSANs        []string
+	removeSANs     []string
+	validity       string
+	certadmConfig  string
+	list           bool
+	names          []string
+	expiringWithin string
+	kubectlConfig  string
+	skipKubectl    bool
+	kubeletServing string
+	kubeletSANs    []string
 }
 
 // NewCmdRenew returns "certadm renew" command.
 func NewCmdRenew() *cobra.Command {
 	opts := &renewOptions{}
 	cmd := &cobra.Command{
-		Use:   "renew",
+		Use:   "renew [name...]",
 		Short: "Run this command in order to renew Kubernetes cluster certificates",
+		Long: "Run this command in order to renew Kubernetes cluster certificates. All the certificates and kubeconfig files are " +
+			"renewed by default, or only the named ones, e.g. 'certadm renew apiserver admin.conf', in which case only the " +
+			"components consuming them are restarted. Use 'certadm renew --list' to list the renewable certificates.",
 		Run: func(cmd *cobra.Command, args []string) {
+			if opts.list {
+				printTargets(os.Stdout)
+				return
+			}
+			opts.names = args
 
-			if opts.configFile != "" {
-				c, err := kubeadm.FetchConfigurationFromConfigFile(opts.configFile)
+			if opts.configFile != "" && opts.configMapFile != "" {
+				klog.Error("'--config' and '--config-from-configmap' are mutually exclusive")
+				os.Exit(1)
+			}
+
+			if opts.configFile != "" || opts.configMapFile != "" {
+				c, err := loadConfig(opts.configFile, opts.configMapFile)
 				if err != nil {
-					klog.Errorf("failed to load config from '--config': [%v]", err)
+					klog.Errorf("failed to load config: [%v]", err)
 					os.Exit(1)
 				}
 
 				if c.CertificatesDir == "" {
-					klog.Warningf("missing the Kubernetes root directory with '--config', so using the default directory %q\n", constants.KubernetesDir)
+					klog.Warningf("missing the Kubernetes root directory in the config, so using the default directory %q\n", constants.KubernetesDir)
 					c.CertificatesDir = filepath.Join(constants.KubernetesDir, "pki")
 				}
 				opts.kubernetesDir = filepath.Dir(c.CertificatesDir)
@@ -51,120 +93,346 @@ func NewCmdRenew() *cobra.Command {
 					opts.criSocketPath = constants.DefaultDockerCRISocket
 				}
 				klog.Infof("[renew] Detected and using CRI socket: %s", opts.criSocketPath)
+			} else {
+				var err error
+				opts.criSocketPath, err = DetectCRISocket(nil)
+				if err != nil {
+					klog.Warningf("[renew] failed to detected and using CRI socket: %v", err)
+					opts.criSocketPath = constants.DefaultDockerCRISocket
+				}
 			}
 
 			if err := opts.run(); err != nil {
 				klog.Error(err)
-				return
+				os.Exit(1)
 			}
 		},
 	}
 
 	cmd.Flags().StringVar(&opts.configFile, "config", "", "Using the config file to renew certificates.")
+	cmd.Flags().StringVar(&opts.configMapFile, "config-from-configmap", "", "Load the cluster configuration from the kube-system/kubeadm-config ConfigMap dumped by 'kubectl get configmap -o yaml'.")
 	cmd.Flags().StringVar(&opts.kubernetesDir, "root-dir", constants.KubernetesDir, "The path save the Kubernetes certificates and kubeconfig.")
+	cmd.Flags().StringVar(&opts.backupDir, "backup-dir", constants.DefaultBackupDir, "The directory to save the backups.")
+	cmd.Flags().StringVar(&opts.backend, "backend", renewal.NativeBackend, "The backend used to renew certificates. One of: native|kubeadm. The kubeadm backend reconstructs the kubeadm config from the existing certificates if '--config' is missing.")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Print the files, commands, containers and services changed by the renewal without touching the disk.")
+	cmd.Flags().StringSliceVar(&opts.addSANs, "add-san", nil, "Add the SAN to the renewed certificate, the format is [apiserver|etcd-server|etcd-peer=]<SAN> and the certificate defaults to apiserver. The flag can be repeated.")
+	cmd.Flags().StringSliceVar(&opts.removeSANs, "remove-san", nil, "Remove the SAN from the renewed certificate, the format is the same as '--add-san'. The flag can be repeated.")
+	cmd.Flags().StringVar(&opts.validity, "validity", "", "The validity period of the renewed certificates, e.g. 8760h, 365d or 2y. It is capped at the expiration of the CA. Defaults to 1y.")
+	cmd.Flags().StringVar(&opts.certadmConfig, "certadm-config", "", "The certadm config file which sets the validity period of every certificate.")
+	cmd.Flags().StringVar(&opts.expiringWithin, "expiring-within", "", "Renew only the certificates and kubeconfig files which expire within the duration, e.g. 30d. Nothing is renewed or restarted if none of them expires.")
+	cmd.Flags().BoolVar(&opts.list, "list", false, "List the certificates and kubeconfig files which can be renewed individually, and the components restarted after the renewal.")
+	addKubectlConfigFlags(cmd.Flags(), &opts.kubectlConfig, &opts.skipKubectl)
+	cmd.Flags().StringVar(&opts.kubeletServing, "kubelet-serving-mode", "", fmt.Sprintf("The kubelet serving certificate is %s or signed by the cluster CA. One of: %s|%s. Defaults to ca if the cluster CA key exists, so the clients can verify it, or self-signed otherwise, e.g. on the worker nodes.", certs.KubeletServingSelfSigned, certs.KubeletServingSelfSigned, certs.KubeletServingCASigned))
+	cmd.Flags().StringSliceVar(&opts.kubeletSANs, "kubelet-serving-san", nil, "The SANs of the kubelet serving certificate, e.g. the node name and the node IPs, the first DNS name is the node name. The flag can be repeated. Defaults to the SANs of the existing certificate or the host name.")
+	cmd.Flags().BoolVar(&opts.rotateKeys, "rotate-keys", false, "Generate new private keys instead of reusing the existing ones. The kubeadm backend always generates new private keys.")
 
 	return cmd
 }
 
 func (o *renewOptions) run() error {
-	certificatesDir := filepath.Join(o.kubernetesDir, "pki")
+	if o.expiringWithin != "" {
+		names, err := o.expiringTargets()
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			fmt.Printf("[renew] No certificate expires within %s, skip the renewal\n", o.expiringWithin)
+			return nil
+		}
+		fmt.Printf("[renew] Renew the certificates expiring within %s: %s\n", o.expiringWithin, strings.Join(names, ", "))
+		o.names = names
+	}
 
-	// 1. backup old certificates to temp dir.
-	fmt.Printf("[renew] Backup old Kubernetes certificates directory %s \n", certificatesDir)
-	if err := certs.BackupCertificates(certificatesDir, ""); err != nil {
-		return err
+	if o.kubeletServing != "" && o.kubeletServing != certs.KubeletServingSelfSigned && o.kubeletServing != certs.KubeletServingCASigned {
+		return errors.Errorf("unknown '--kubelet-serving-mode' %q, one of: %s|%s", o.kubeletServing, certs.KubeletServingSelfSigned, certs.KubeletServingCASigned)
 	}
 
-	// 2. remove old certificates exclude CA and sa.
-	fmt.Println("[renew] Remove old Kubernetes certificates exclude CA and sa")
-	if err := certs.RemoveOldCertificates(certificatesDir); err != nil {
+	sanChanges, err := certs.ParseSANChanges(o.addSANs, o.removeSANs)
+	if err != nil {
 		return err
 	}
-
-	// 3. remove old kubeconfig
-	fmt.Println("[renew] Remove old kubeconfig file")
-	if err := kubeconfig.RemoveOldKubeconfig(o.kubernetesDir); err != nil {
+	validity, err := o.loadValidity()
+	if err != nil {
 		return err
 	}
 
-	// 4. renew certificates and kubeconfig
-	fmt.Println("[renew] Renew Kubernetes certificates")
-	if err := certs.RenewCertificate(o.configFile); err != nil {
+	var reconstructed *kubeadm.Config
+	if o.backend == renewal.KubeadmBackend && o.configFile == "" {
+		reconstructed, err = reconstructConfig(o.kubernetesDir, o.criSocketPath, o.configMapFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to reconstruct the kubeadm config, please use '--config'")
+		}
+		config.ApplySANChanges(reconstructed, sanChanges)
+		fmt.Printf("[renew] Missing '--config', using the kubeadm config reconstructed from %s, see 'certadm config generate'\n", o.kubernetesDir)
+		o.configFile = reconstructedConfigFile
+		if !o.dryRun {
+			configFile, err := writeTempConfigFile(reconstructed)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(configFile)
+			o.configFile = configFile
+		}
+	}
+
+	renewOpts := &renewal.Options{
+		Backend:       o.backend,
+		KubernetesDir: o.kubernetesDir,
+		ConfigFile:    o.configFile,
+		Config:        reconstructed,
+		RotateKeys:    o.rotateKeys,
+		SANChanges:    sanChanges,
+		Validity:      validity,
+		Names:         o.names,
+	}
+	renewer, err := renewal.NewRenewer(renewOpts)
+	if err != nil {
 		return err
 	}
 
-	fmt.Println("[renew] Renew Kubernetes components kubeconfig")
-	if err := kubeconfig.RenewKubeConfigFile(o.configFile); err != nil {
+	diffs, err := renewer.SANDiffs()
+	if err != nil {
 		return err
 	}
+	printSANDiffs(os.Stdout, diffs)
+	if err := certs.ValidateSANDiffs(diffs, sanChanges); err != nil {
+		return errors.Wrap(err, "refuse to renew the certificates")
+	}
+
+	if o.dryRun {
+		return o.printPlan(os.Stdout, renewer)
+	}
 
-	// 5. remove kubelet certificates
-	fmt.Println("[renew] Remove old kubelet certificates")
-	if err := certs.RemoveKubeletCertificate(constants.KubeletCertificatesPath); err != nil {
+	// 1. backup old certificates and kubeconfig to the backup dir.
+	b, err := backup.Create(o.backupDir, o.kubernetesDir, constants.KubeletCertificatesPath)
+	if err != nil {
 		return err
 	}
+	fmt.Printf("[renew] Backup old Kubernetes certificates and kubeconfig to %s, use 'certadm rollback %s' to restore them\n", b.Path, b.Name)
 
-	// 6. copy new admin.conf to $HOME/.kube/config
-	fmt.Println("[renew] Copy admin.conf to $HOME/.kube/config")
-	if err := kubeconfig.CreateKubectlKubeConfig(o.kubernetesDir); err != nil {
+	if err := o.renew(renewOpts); err != nil {
+		fmt.Printf("[renew] Failed to renew, restore Kubernetes certificates and kubeconfig from %s\n", b.Path)
+		if restoreErr := backup.Restore(b, o.kubernetesDir, constants.KubeletCertificatesPath); restoreErr != nil {
+			return errors.Wrapf(restoreErr, "renew failed: %v; restoring backup %s also failed", err, b.Name)
+		}
 		return err
 	}
 
-	// 7. restart control-plane components and kubelet service
-	// Try to restart control plane components
-	if err := removeContainers(utilsexec.New(), o.criSocketPath); err != nil {
-		klog.Errorf("[renew] failed to stop the control plane containers, %v \n", err)
-		klog.Warningln("[renew] please stop the control plane containers manually")
+	// 5. restart control-plane components and kubelet service
+	if len(o.names) == 0 {
+		restartControlPlane(o.kubernetesDir, o.criSocketPath)
+		return nil
+	}
+	// only the files of the named certificates are replaced, the PKI directories mounted by the other
+	// components are kept, so restarting the consumers is enough.
+	components := renewal.Consumers(o.names)
+	if len(components) == 0 {
+		fmt.Println("[restart] No component consumes the renewed certificates, skip the restart")
+		return nil
 	}
+	restartComponents(o.kubernetesDir, o.criSocketPath, components)
+	return nil
+}
 
-	fmt.Printf("[renew] waiting for the kubelet to boot up the control plane as Static Pods from %s/manifests \n", o.kubernetesDir)
-	if err := util.WaitForContainersRunning(constants.ControlPlaneNames); err != nil {
-		klog.Errorf("[renew] failed to waiting for containers running: [%v]\n", err)
-		klog.Warningln("[renew] please ensure control plane running by docker or crictl")
-	}else {
-		klog.Infoln("[renew] kubernetes-manager containers running")
+// renew changes the certificates and kubeconfig files, the caller restores the backup when it fails.
+func (o *renewOptions) renew(renewOpts *renewal.Options) error {
+	// 2. renew certificates and kubeconfig exclude CA and sa.
+	fmt.Printf("[renew] Renew Kubernetes certificates and kubeconfig using the %s backend\n", o.backend)
+	if err := renewal.Run(renewOpts); err != nil {
+		return err
 	}
 
-	// Try to restart the kubelet service
-	klog.V(1).Infoln("[renew] getting init system")
-	initSystem, err := initsystem.GetInitSystem()
-	if err != nil {
-		klog.Warningln("[renew] the kubelet service could not restarted by certadm. Unable to detect a supported init system!")
-		klog.Warningln("[renew] please ensure kubelet is restarted manually")
-	} else {
-		fmt.Println("[renew] restarting the kubelet service")
-		if err := initSystem.ServiceRestart("kubelet"); err != nil {
-			klog.Warningf("[renew] the kubelet service could not be restarted by certadm: [%v]\n", err)
-			klog.Warningln("[renew] please ensure kubelet is restarted manually")
+	// 3. renew or remove kubelet certificates
+	if certs.Selected(o.names, "kubelet.conf") {
+		kubeletOpts := &certs.RenewOptions{RotateKeys: o.rotateKeys, Validity: renewOpts.Validity}
+		if err := renewKubeletCertificates(o.kubernetesDir, kubeletOpts); err != nil {
+			return err
 		}
-
-		fmt.Println("[renew] ensure the kubelet service is active")
-		if err := util.WaitForServiceActive("kubelet", constants.ServiceCallRetryInterval, constants.ServiceCallTimeout); err != nil {
-			klog.Warningln("[wait-service] please ensure kubelet is active manually")
+	}
+	if certs.Selected(o.names, certs.KubeletServingName) {
+		fmt.Println("[certs] Renew the kubelet serving certificate")
+		if err := certs.RenewKubeletServingCertificate(constants.KubeletCertificatesPath, filepath.Join(o.kubernetesDir, "pki"), &certs.KubeletServingOptions{
+			Mode:       o.kubeletServing,
+			SANs:       o.kubeletSANs,
+			RotateKeys: o.rotateKeys,
+			Validity:   renewOpts.Validity,
+		}); err != nil {
+			return err
 		}
 	}
 
+	// 4. merge the new admin credentials into the kubectl kubeconfig
+	if certs.Selected(o.names, "admin.conf") && !o.skipKubectl {
+		fmt.Printf("[renew] Merge the admin credentials of admin.conf into %s\n", o.kubectlConfig)
+		return mergeKubectlConfig(o.kubernetesDir, o.kubectlConfig)
+	}
 	return nil
 }
 
-func DetectCRISocket(cfg *kubeadm.Config) (string, error) {
-	if cfg != nil && cfg.CRISocket != "" {
-		return cfg.CRISocket, nil
+// kubeletClientCertificateFile returns the kubelet client certificate file referred by kubelet.conf, it returns
+// empty if kubelet.conf not exists or embeds the client certificate.
+func kubeletClientCertificateFile(kubernetesDir string) (string, error) {
+	kubeletConf := filepath.Join(kubernetesDir, "kubelet.conf")
+	if exists, err := path.Exists(path.CheckFollowSymlink, kubeletConf); err != nil || !exists {
+		return "", err
 	}
+	return kubeconfig.ClientCertificateFile(kubeletConf)
+}
 
-	return utilruntime.DetectCRISocket()
+// renewKubeletCertificates renews the kubelet client certificate if kubelet.conf refers to it, e.g. the
+// kubelet-client-current.pem of the kubelet certificate rotation, otherwise kubelet.conf embeds the renewed
+// client certificate and the kubelet certificates are removed, so the kubelet recreates them.
+func renewKubeletCertificates(kubernetesDir string, o *certs.RenewOptions) error {
+	certFile, err := kubeletClientCertificateFile(kubernetesDir)
+	if err != nil {
+		return err
+	}
+	if certFile != "" {
+		fmt.Printf("[certs] kubelet.conf refers to %s, renew the kubelet client certificate\n", certFile)
+		_, err := certs.RenewKubeletClientCertificate(certFile, filepath.Join(kubernetesDir, "pki"), o)
+		return err
+	}
+
+	fmt.Println("[certs] Remove old kubelet certificates")
+	return certs.RemoveKubeletCertificate(constants.KubeletCertificatesPath)
 }
 
-func removeContainers(execer utilsexec.Interface, criSocketPath string) error {
-	containerRuntime, err := utilruntime.NewContainerRuntime(execer, criSocketPath)
+// addKubectlConfigFlags adds the flags to choose the kubectl kubeconfig file which the admin credentials are merged into.
+func addKubectlConfigFlags(flags *pflag.FlagSet, kubectlConfig *string, skip *bool) {
+	flags.StringVar(kubectlConfig, "kubectl-config", kubeconfig.KubectlKubeConfigPath(), "The kubectl kubeconfig file which the renewed admin credentials are merged into, the other clusters, users and contexts in it are untouched.")
+	flags.BoolVar(skip, "skip-kubectl-config", false, "Do not update the kubectl kubeconfig file.")
+}
+
+// mergeKubectlConfig merges the admin credentials of admin.conf into the kubectl kubeconfig file.
+func mergeKubectlConfig(kubernetesDir, kubectlConfig string) error {
+	backupPath, err := kubeconfig.MergeKubectlKubeConfig(kubernetesDir, kubectlConfig)
 	if err != nil {
 		return err
 	}
-	klog.V(1).Infof("container runtime %v", containerRuntime)
-	containers, err := containerRuntime.ListKubeContainers()
+	if backupPath != "" {
+		fmt.Printf("[kubeconfig] Backup the original %s to %s\n", kubectlConfig, backupPath)
+	}
+	return nil
+}
+
+// expiringTargets returns the names of the certificates and kubeconfig files which expire within '--expiring-within',
+// only the certificates in the names given in the command line are checked if any.
+func (o *renewOptions) expiringTargets() ([]string, error) {
+	within, err := util.ParseDuration(o.expiringWithin)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid '--expiring-within' %q", o.expiringWithin)
+	}
+	if within <= 0 {
+		return nil, errors.Errorf("invalid '--expiring-within' %q, it must be positive", o.expiringWithin)
+	}
+	if err := renewal.ValidateTargets(o.names); err != nil {
+		return nil, err
+	}
+	return renewal.ExpiringTargets(o.kubernetesDir, within, o.names)
+}
+
+// loadValidity returns the validity period of the renewed certificates from '--validity' and '--certadm-config'.
+func (o *renewOptions) loadValidity() (*certs.Validity, error) {
+	var c *v1alpha1.CertadmConfiguration
+	if o.certadmConfig != "" {
+		var err error
+		c, err = config.LoadCertadmConfigFile(o.certadmConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return config.NewValidity(c, o.validity)
+}
+
+// printSANDiffs prints the SANs added to and removed from the certificates by the renewal.
+func printSANDiffs(out io.Writer, diffs []*certs.SANDiff) {
+	for _, d := range diffs {
+		fmt.Fprintf(out, "[renew] SANs of the %s certificate: %s\n", d.Name, d)
+	}
+}
+
+// printPlan prints every step of the renewal without touching the disk.
+func (o *renewOptions) printPlan(out io.Writer, renewer renewal.Renewer) error {
+	backupFiles, err := backup.SourceFiles(o.kubernetesDir, constants.KubeletCertificatesPath)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "[dry-run] Would back up the following files to %s:\n", o.backupDir)
+	printList(out, backupFiles)
+
+	plan, err := renewer.Plan()
 	if err != nil {
 		return err
 	}
-	klog.Infof("kubernetes-manager containers: %v", containers)
-	return containerRuntime.RemoveContainers(containers)
+	fmt.Fprintln(out, "[dry-run] Would remove the following files:")
+	printList(out, plan.RemovedFiles)
+	fmt.Fprintf(out, "[dry-run] Would regenerate the following files using the %s backend:\n", o.backend)
+	printList(out, plan.RenewedFiles)
+	fmt.Fprintln(out, "[dry-run] Would execute the following commands:")
+	commands := []string{}
+	for _, c := range plan.Commands {
+		commands = append(commands, strings.Join(c, " "))
+	}
+	printList(out, commands)
+
+	if certs.Selected(o.names, "kubelet.conf") {
+		certFile, err := kubeletClientCertificateFile(o.kubernetesDir)
+		if err != nil {
+			return err
+		}
+		if certFile != "" {
+			fmt.Fprintf(out, "[dry-run] Would renew the kubelet client certificate %s referred by kubelet.conf\n", certFile)
+		} else {
+			kubeletFiles, err := certs.KubeletCertificates(constants.KubeletCertificatesPath)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, "[dry-run] Would remove the following kubelet certificates:")
+			printList(out, kubeletFiles)
+		}
+	}
+	if certs.Selected(o.names, certs.KubeletServingName) {
+		fmt.Fprintf(out, "[dry-run] Would renew the kubelet serving certificate %s\n", certs.KubeletServingCertificatePath(constants.KubeletCertificatesPath))
+	}
+
+	if certs.Selected(o.names, "admin.conf") && !o.skipKubectl {
+		fmt.Fprintf(out, "[dry-run] Would merge the admin credentials of %s into %s\n", filepath.Join(o.kubernetesDir, "admin.conf"), o.kubectlConfig)
+	}
+
+	components := renewal.Consumers(o.names)
+	if len(o.names) == 0 {
+		components = append(append([]string{}, constants.ControlPlaneNames...), renewal.KubeletService)
+	}
+	printRestartPlan(out, utilsexec.New(), o.criSocketPath, components)
+	return nil
+}
+
+// printTargets prints the certificates and kubeconfig files which can be renewed individually.
+func printTargets(out io.Writer) {
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFILE\tRESTARTS")
+	for _, t := range renewal.Targets() {
+		restarts := strings.Join(t.Consumers, ",")
+		if restarts == "" {
+			restarts = "<none>"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, t.File, restarts)
+	}
+	w.Flush()
+}
+
+// loadConfig loads the kubeadm Config from the kubeadm config file or the dumped kubeadm-config ConfigMap.
+func loadConfig(configFile, configMapFile string) (*kubeadm.Config, error) {
+	if configFile != "" {
+		return kubeadm.FetchConfigurationFromConfigFile(configFile)
+	}
+	return config.FromConfigMapFile(configMapFile)
+}
+
+func DetectCRISocket(cfg *kubeadm.Config) (string, error) {
+	if cfg != nil && cfg.CRISocket != "" {
+		return cfg.CRISocket, nil
+	}
+
+	return utilruntime.DetectCRISocket()
 }
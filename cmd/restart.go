@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/renewal"
+	"github.com/pytimer/certadm/pkg/util"
+	utilruntime "github.com/pytimer/certadm/pkg/util/runtime"
+
+	"k8s.io/klog"
+	"k8s.io/kubernetes/pkg/util/initsystem"
+	utilsexec "k8s.io/utils/exec"
+)
+
+// restartControlPlane removes the control plane containers, so the kubelet recreates them
+// from the static pod manifests, and restarts the kubelet service.
+func restartControlPlane(kubernetesDir, criSocketPath string) {
+	restartComponents(kubernetesDir, criSocketPath, append(append([]string{}, constants.ControlPlaneNames...), renewal.KubeletService))
+}
+
+// restartComponents removes the containers of the control plane components, and restarts the kubelet service
+// if the components contain it.
+func restartComponents(kubernetesDir, criSocketPath string, components []string) {
+	containers, restartKubelet := splitComponents(components)
+	if len(containers) > 0 {
+		restartContainers(kubernetesDir, criSocketPath, containers)
+	}
+	if restartKubelet {
+		restartKubeletService()
+	}
+}
+
+func restartContainers(kubernetesDir, criSocketPath string, components []string) {
+	// Try to restart control plane components
+	if err := removeContainers(utilsexec.New(), criSocketPath, components); err != nil {
+		klog.Errorf("[restart] failed to stop the control plane containers, %v \n", err)
+		klog.Warningln("[restart] please stop the control plane containers manually")
+	}
+
+	fmt.Printf("[restart] waiting for the kubelet to boot up the control plane as Static Pods from %s/manifests \n", kubernetesDir)
+	if err := util.WaitForContainersRunning(components); err != nil {
+		klog.Errorf("[restart] failed to waiting for containers running: [%v]\n", err)
+		klog.Warningln("[restart] please ensure control plane running by docker or crictl")
+	} else {
+		klog.Infoln("[restart] kubernetes-manager containers running")
+	}
+}
+
+func restartKubeletService() {
+	// Try to restart the kubelet service
+	klog.V(1).Infoln("[restart] getting init system")
+	initSystem, err := initsystem.GetInitSystem()
+	if err != nil {
+		klog.Warningln("[restart] the kubelet service could not restarted by certadm. Unable to detect a supported init system!")
+		klog.Warningln("[restart] please ensure kubelet is restarted manually")
+	} else {
+		fmt.Println("[restart] restarting the kubelet service")
+		if err := initSystem.ServiceRestart("kubelet"); err != nil {
+			klog.Warningf("[restart] the kubelet service could not be restarted by certadm: [%v]\n", err)
+			klog.Warningln("[restart] please ensure kubelet is restarted manually")
+		}
+
+		fmt.Println("[restart] ensure the kubelet service is active")
+		if err := util.WaitForServiceActive("kubelet", constants.ServiceCallRetryInterval, constants.ServiceCallTimeout); err != nil {
+			klog.Warningln("[wait-service] please ensure kubelet is active manually")
+		}
+	}
+}
+
+func removeContainers(execer utilsexec.Interface, criSocketPath string, components []string) error {
+	containerRuntime, err := utilruntime.NewContainerRuntime(execer, criSocketPath)
+	if err != nil {
+		return err
+	}
+	klog.V(1).Infof("container runtime %v", containerRuntime)
+	containers, err := containerRuntime.ListKubeContainers(components...)
+	if err != nil {
+		return err
+	}
+	klog.Infof("kubernetes-manager containers: %v", containers)
+	return containerRuntime.RemoveContainers(containers)
+}
+
+// printRestartPlan prints the containers and services restarted by restartComponents.
+func printRestartPlan(out io.Writer, execer utilsexec.Interface, criSocketPath string, components []string) {
+	components, restartKubelet := splitComponents(components)
+	if len(components) == 0 {
+		fmt.Fprintln(out, "[dry-run] Would not remove any control plane container")
+	} else if containerRuntime, err := utilruntime.NewContainerRuntime(execer, criSocketPath); err != nil {
+		fmt.Fprintf(out, "[dry-run] Would fail to remove the control plane containers: %v\n", err)
+	} else if containers, err := containerRuntime.ListKubeContainers(components...); err != nil {
+		fmt.Fprintf(out, "[dry-run] Would fail to list the control plane containers: %v\n", err)
+	} else {
+		fmt.Fprintf(out, "[dry-run] Would remove the following control plane containers using the CRI socket %s:\n", criSocketPath)
+		printList(out, containers)
+	}
+
+	if !restartKubelet {
+		fmt.Fprintln(out, "[dry-run] Would not restart any service")
+	} else if _, err := initsystem.GetInitSystem(); err != nil {
+		fmt.Fprintln(out, "[dry-run] Would not restart the kubelet service, unable to detect a supported init system")
+	} else {
+		fmt.Fprintln(out, "[dry-run] Would restart the following services:")
+		printList(out, []string{renewal.KubeletService})
+	}
+}
+
+// splitComponents returns the control plane components which run as static pods, and whether the
+// components contain the kubelet service.
+func splitComponents(components []string) ([]string, bool) {
+	containers := []string{}
+	restartKubelet := false
+	for _, c := range components {
+		if c == renewal.KubeletService {
+			restartKubelet = true
+			continue
+		}
+		containers = append(containers, c)
+	}
+	return containers, restartKubelet
+}
+
+func printList(out io.Writer, items []string) {
+	if len(items) == 0 {
+		fmt.Fprintln(out, "\t<none>")
+	}
+	for _, item := range items {
+		fmt.Fprintf(out, "\t%s\n", item)
+	}
+}
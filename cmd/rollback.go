@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pytimer/certadm/pkg/backup"
+	"github.com/pytimer/certadm/pkg/constants"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+)
+
+type rollbackOptions struct {
+	kubernetesDir string
+	backupDir     string
+	criSocketPath string
+	list          bool
+}
+
+// NewCmdRollback returns "certadm rollback" command.
+func NewCmdRollback() *cobra.Command {
+	opts := &rollbackOptions{}
+	cmd := &cobra.Command{
+		Use:   "rollback [backup-name]",
+		Short: "Restore the Kubernetes certificates and kubeconfig from the backup taken by certadm",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if opts.list {
+				if err := printBackups(os.Stdout, opts.backupDir); err != nil {
+					klog.Error(err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			if len(args) == 0 {
+				klog.Error("please choose a backup to restore, use 'certadm rollback --list' to list the backups")
+				os.Exit(1)
+			}
+
+			var err error
+			opts.criSocketPath, err = DetectCRISocket(nil)
+			if err != nil {
+				klog.Warningf("[rollback] failed to detected and using CRI socket: %v", err)
+				opts.criSocketPath = constants.DefaultDockerCRISocket
+			}
+
+			if err := opts.run(args[0]); err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.kubernetesDir, "root-dir", constants.KubernetesDir, "The path save the Kubernetes certificates and kubeconfig.")
+	cmd.Flags().StringVar(&opts.backupDir, "backup-dir", constants.DefaultBackupDir, "The directory to save the backups.")
+	cmd.Flags().BoolVar(&opts.list, "list", false, "List the backups.")
+
+	return cmd
+}
+
+func (o *rollbackOptions) run(name string) error {
+	b, err := backup.Get(o.backupDir, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[rollback] Restore Kubernetes certificates and kubeconfig from %s\n", b.Path)
+	if err := backup.Restore(b, o.kubernetesDir, constants.KubeletCertificatesPath); err != nil {
+		return errors.Wrapf(err, "failed to restore the backup %s", b.Name)
+	}
+
+	restartControlPlane(o.kubernetesDir, o.criSocketPath)
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pytimer/certadm/pkg/backup"
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/util/staticpod"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+)
+
+// serviceAccountKeyFileFlag is the kube-apiserver flag of the public keys which verify the service account tokens.
+const serviceAccountKeyFileFlag = "service-account-key-file"
+
+// NewCmdSA returns "certadm sa" command.
+func NewCmdSA() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sa",
+		Short: "Manage the service account signing key",
+	}
+
+	cmd.AddCommand(NewCmdSARotate())
+	return cmd
+}
+
+type saRotateOptions struct {
+	kubernetesDir string
+	backupDir     string
+	criSocketPath string
+	finalize      bool
+}
+
+// NewCmdSARotate returns "certadm sa rotate" command.
+func NewCmdSARotate() *cobra.Command {
+	opts := &saRotateOptions{}
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate the service account signing key",
+		Long: "Rotate the service account signing key. A new sa.key and sa.pub are generated, the old public key is moved to " +
+			"sa-old.pub and added to the kube-apiserver static pod manifest by an extra '--service-account-key-file', so the " +
+			"tokens signed by the old key are still accepted. Run 'certadm sa rotate --finalize' to remove the old public key " +
+			"once all the tokens are signed by the new key.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			opts.criSocketPath, err = DetectCRISocket(nil)
+			if err != nil {
+				klog.Warningf("[sa] failed to detected and using CRI socket: %v", err)
+				opts.criSocketPath = constants.DefaultDockerCRISocket
+			}
+
+			run := opts.run
+			if opts.finalize {
+				run = opts.runFinalize
+			}
+			if err := run(); err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.kubernetesDir, "root-dir", constants.KubernetesDir, "The path save the Kubernetes certificates and kubeconfig.")
+	cmd.Flags().StringVar(&opts.backupDir, "backup-dir", constants.DefaultBackupDir, "The directory to save the backups.")
+	cmd.Flags().BoolVar(&opts.finalize, "finalize", false, "Remove the old public key, run it once all the service account tokens are signed by the new key.")
+
+	return cmd
+}
+
+func (o *saRotateOptions) run() error {
+	certificatesDir := filepath.Join(o.kubernetesDir, "pki")
+	manifestPath := staticpod.ManifestPath(filepath.Join(o.kubernetesDir, "manifests"), "kube-apiserver")
+	oldPublicKeyFile, err := o.oldPublicKeyFile(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	b, err := backup.Create(o.backupDir, o.kubernetesDir, constants.KubeletCertificatesPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[sa] Backup old Kubernetes certificates and kubeconfig to %s, use 'certadm rollback %s' to restore them\n", b.Path, b.Name)
+
+	fmt.Println("[sa] Rotate the service account key")
+	err = certs.RotateServiceAccountKey(certificatesDir)
+	if err == nil {
+		fmt.Printf("[sa] Add '--%s=%s' to %s\n", serviceAccountKeyFileFlag, oldPublicKeyFile, manifestPath)
+		err = staticpod.AppendFlag(manifestPath, serviceAccountKeyFileFlag, oldPublicKeyFile)
+	}
+	if err != nil {
+		fmt.Printf("[sa] Failed to rotate the service account key, restore Kubernetes certificates and kubeconfig from %s\n", b.Path)
+		if restoreErr := backup.Restore(b, o.kubernetesDir, constants.KubeletCertificatesPath); restoreErr != nil {
+			return errors.Wrapf(restoreErr, "rotate failed: %v; restoring backup %s also failed", err, b.Name)
+		}
+		return err
+	}
+
+	// the kube-apiserver verifies the tokens and the kube-controller-manager signs them.
+	restartComponents(o.kubernetesDir, o.criSocketPath, []string{"kube-apiserver", "kube-controller-manager"})
+
+	fmt.Println("[sa] The kube-apiserver accepts the tokens signed by the old and the new key now, run 'certadm sa rotate --finalize' " +
+		"once all the tokens are signed by the new key")
+	return nil
+}
+
+func (o *saRotateOptions) runFinalize() error {
+	certificatesDir := filepath.Join(o.kubernetesDir, "pki")
+	manifestPath := staticpod.ManifestPath(filepath.Join(o.kubernetesDir, "manifests"), "kube-apiserver")
+	oldPublicKeyFile, err := o.oldPublicKeyFile(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	b, err := backup.Create(o.backupDir, o.kubernetesDir, constants.KubeletCertificatesPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[sa] Backup old Kubernetes certificates and kubeconfig to %s, use 'certadm rollback %s' to restore them\n", b.Path, b.Name)
+
+	// remove the flag first, so the kube-apiserver never refers to the missing public key.
+	flagRemoved, err := staticpod.RemoveFlag(manifestPath, serviceAccountKeyFileFlag, oldPublicKeyFile)
+	if flagRemoved {
+		fmt.Printf("[sa] Removed '--%s=%s' from %s\n", serviceAccountKeyFileFlag, oldPublicKeyFile, manifestPath)
+	}
+	keyRemoved := false
+	if err == nil {
+		keyRemoved, err = certs.FinalizeServiceAccountKeyRotation(certificatesDir)
+	}
+	if err != nil {
+		fmt.Printf("[sa] Failed to finalize the service account key rotation, restore Kubernetes certificates and kubeconfig from %s\n", b.Path)
+		if restoreErr := backup.Restore(b, o.kubernetesDir, constants.KubeletCertificatesPath); restoreErr != nil {
+			return errors.Wrapf(restoreErr, "finalize failed: %v; restoring backup %s also failed", err, b.Name)
+		}
+		return err
+	}
+	if !flagRemoved && !keyRemoved {
+		fmt.Println("[sa] No service account key rotation is in progress, nothing to finalize")
+		return nil
+	}
+
+	restartComponents(o.kubernetesDir, o.criSocketPath, []string{"kube-apiserver"})
+	return nil
+}
+
+// oldPublicKeyFile returns the path of sa-old.pub in the kube-apiserver container, it is in the same directory
+// as the public key passed to the kube-apiserver.
+func (o *saRotateOptions) oldPublicKeyFile(manifestPath string) (string, error) {
+	values, err := staticpod.GetFlagValues(manifestPath, serviceAccountKeyFileFlag)
+	if err != nil {
+		return "", err
+	}
+	if len(values) == 0 {
+		return "", errors.Errorf("the kube-apiserver static pod manifest %s has no '--%s'", manifestPath, serviceAccountKeyFileFlag)
+	}
+	return filepath.Join(filepath.Dir(values[0]), constants.ServiceAccountOldPublicKeyBaseName+".pub"), nil
+}
@@ -0,0 +1,414 @@
+package backup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/kubeadm"
+	"github.com/pytimer/certadm/pkg/kubeconfig"
+	"github.com/pytimer/certadm/pkg/util"
+	"github.com/pytimer/certadm/pkg/version"
+
+	"github.com/otiai10/copy"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+const (
+	// backupNameFormat is the time layout of the backup name
+	backupNameFormat = "20060102150405"
+
+	archiveSuffix  = ".tar.gz"
+	manifestSuffix = ".manifest.yaml"
+
+	pkiDirName        = "pki"
+	kubeconfigDirName = "kubeconfig"
+	kubeletPKIDirName = "kubelet-pki"
+	manifestsDirName  = "manifests"
+)
+
+// staticPodManifests is the static pod manifests edited by certadm, e.g. the service account public keys of
+// the kube-apiserver.
+var staticPodManifests = []string{"kube-apiserver.yaml"}
+
+// Backup is a tar.gz archive of the Kubernetes certificates, kubeconfig files, static pod manifests edited by
+// certadm and kubelet certificates, and the manifest which describes it.
+type Backup struct {
+	Name      string
+	Path      string
+	CreatedAt time.Time
+	Manifest  *Manifest
+}
+
+// Create backups the PKI directory, the kubeconfig files and the static pod manifests edited by certadm in the
+// kubernetesDir and the kubelet PKI directory into a new archive in the backupDir.
+func Create(backupDir, kubernetesDir, kubeletPKIDir string) (*Backup, error) {
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	name := now.Format(backupNameFormat)
+	for i := 1; ; i++ {
+		if exists, err := path.Exists(path.CheckFollowSymlink, filepath.Join(backupDir, name+manifestSuffix)); err != nil {
+			return nil, err
+		} else if !exists {
+			break
+		}
+		name = fmt.Sprintf("%s-%d", now.Format(backupNameFormat), i)
+	}
+
+	stagingDir, err := ioutil.TempDir(backupDir, ".staging-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	klog.V(2).Infof("[backup] Backup %s and %s to %s", kubernetesDir, kubeletPKIDir, backupDir)
+	if err := copy.Copy(filepath.Join(kubernetesDir, pkiDirName), filepath.Join(stagingDir, pkiDirName)); err != nil {
+		return nil, errors.Wrap(err, "failed to backup the certificates")
+	}
+
+	for _, kf := range kubeconfig.KubeConfigFiles {
+		src := filepath.Join(kubernetesDir, kf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, src); err != nil {
+			return nil, err
+		} else if !exists {
+			continue
+		}
+		if err := copy.Copy(src, filepath.Join(stagingDir, kubeconfigDirName, kf)); err != nil {
+			return nil, errors.Wrapf(err, "failed to backup the kubeconfig %s", src)
+		}
+	}
+
+	for _, mf := range staticPodManifests {
+		src := filepath.Join(kubernetesDir, manifestsDirName, mf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, src); err != nil {
+			return nil, err
+		} else if !exists {
+			continue
+		}
+		if err := copy.Copy(src, filepath.Join(stagingDir, manifestsDirName, mf)); err != nil {
+			return nil, errors.Wrapf(err, "failed to backup the static pod manifest %s", src)
+		}
+	}
+
+	if exists, err := path.Exists(path.CheckFollowSymlink, kubeletPKIDir); err != nil {
+		return nil, err
+	} else if exists {
+		if err := copy.Copy(kubeletPKIDir, filepath.Join(stagingDir, kubeletPKIDirName)); err != nil {
+			return nil, errors.Wrap(err, "failed to backup the kubelet certificates")
+		}
+	}
+
+	m, err := newManifest(name, now, stagingDir, kubernetesDir, kubeletPKIDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the backup manifest")
+	}
+
+	archivePath := filepath.Join(backupDir, name+archiveSuffix)
+	if err := writeArchive(archivePath+".tmp", stagingDir); err != nil {
+		os.Remove(archivePath + ".tmp")
+		return nil, errors.Wrap(err, "failed to write the backup archive")
+	}
+	if err := os.Rename(archivePath+".tmp", archivePath); err != nil {
+		return nil, err
+	}
+	if err := writeManifest(filepath.Join(backupDir, name+manifestSuffix), m); err != nil {
+		return nil, errors.Wrap(err, "failed to write the backup manifest")
+	}
+
+	return &Backup{Name: name, Path: archivePath, CreatedAt: now, Manifest: m}, nil
+}
+
+// SourceFiles returns the files which are backed up by Create.
+func SourceFiles(kubernetesDir, kubeletPKIDir string) ([]string, error) {
+	files, err := walkFiles(filepath.Join(kubernetesDir, pkiDirName))
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfigFiles, err := kubeconfig.ListKubeConfigFiles(kubernetesDir)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, kubeconfigFiles...)
+
+	for _, mf := range staticPodManifests {
+		p := filepath.Join(kubernetesDir, manifestsDirName, mf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, p); err != nil {
+			return nil, err
+		} else if exists {
+			files = append(files, p)
+		}
+	}
+
+	kubeletFiles, err := walkFiles(kubeletPKIDir)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, kubeletFiles...), nil
+}
+
+func walkFiles(dir string) ([]string, error) {
+	files := []string{}
+	if exists, err := path.Exists(path.CheckFollowSymlink, dir); err != nil {
+		return nil, err
+	} else if !exists {
+		return files, nil
+	}
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func newManifest(name string, createdAt time.Time, root, kubernetesDir, kubeletPKIDir string) (*Manifest, error) {
+	m := &Manifest{
+		Kind:           ManifestKind,
+		APIVersion:     ManifestAPIVersion,
+		Name:           name,
+		CreatedAt:      createdAt,
+		CertadmVersion: version.Version,
+		KubernetesDir:  kubernetesDir,
+		KubeletPKIDir:  kubeletPKIDir,
+		Files:          []File{},
+	}
+
+	if v, err := kubeadm.GetKubeadmVersion(); err != nil {
+		klog.V(1).Infof("[backup] failed to get the kubeadm version: %v", err)
+	} else {
+		m.KubeadmVersion = v
+	}
+	if hostname, err := os.Hostname(); err != nil {
+		klog.V(1).Infof("[backup] failed to get the hostname: %v", err)
+	} else {
+		m.Hostname = hostname
+	}
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := newFile(root, p)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, f)
+		return nil
+	})
+	return m, err
+}
+
+// List returns the backups in the backupDir, the latest backup is the first.
+func List(backupDir string) ([]*Backup, error) {
+	backups := []*Backup{}
+	infos, err := ioutil.ReadDir(backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return backups, nil
+		}
+		return nil, err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), manifestSuffix) {
+			continue
+		}
+		m, err := loadManifest(filepath.Join(backupDir, info.Name()))
+		if err != nil {
+			klog.Warningf("[backup] %v, skip it", err)
+			continue
+		}
+		backups = append(backups, &Backup{
+			Name:      m.Name,
+			Path:      filepath.Join(backupDir, m.Name+archiveSuffix),
+			CreatedAt: m.CreatedAt,
+			Manifest:  m,
+		})
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Get returns the backup with the name in the backupDir.
+func Get(backupDir, name string) (*Backup, error) {
+	backups, err := List(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+	return nil, errors.Errorf("backup %q not found in %s", name, backupDir)
+}
+
+// Prune removes the backups older than maxAge and the backups exceed the keep count, the latest
+// backups are kept. Zero keep or maxAge means no limit. It returns the removed backups.
+func Prune(backupDir string, keep int, maxAge time.Duration) ([]*Backup, error) {
+	backups, err := List(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []*Backup{}
+	for i, b := range backups {
+		if (keep <= 0 || i < keep) && (maxAge <= 0 || time.Since(b.CreatedAt) <= maxAge) {
+			continue
+		}
+		if err := remove(backupDir, b); err != nil {
+			return removed, errors.Wrapf(err, "failed to remove the backup %s", b.Name)
+		}
+		removed = append(removed, b)
+	}
+	return removed, nil
+}
+
+func remove(backupDir string, b *Backup) error {
+	if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(filepath.Join(backupDir, b.Name+manifestSuffix))
+}
+
+// Restore restores the PKI directory, the kubeconfig files, the static pod manifests and the kubelet PKI directory
+// from the backup. The files are verified with the checksums in the manifest before restoring.
+func Restore(b *Backup, kubernetesDir, kubeletPKIDir string) error {
+	extractDir, err := ioutil.TempDir(filepath.Dir(b.Path), ".restore-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(extractDir)
+
+	klog.V(2).Infof("[backup] Extract %s to %s", b.Path, extractDir)
+	if err := extractArchive(b.Path, extractDir); err != nil {
+		return errors.Wrapf(err, "failed to extract the backup archive %s", b.Path)
+	}
+	if err := verify(b.Manifest, extractDir); err != nil {
+		return err
+	}
+
+	klog.V(2).Infof("[backup] Restore %s to %s and %s", b.Name, kubernetesDir, kubeletPKIDir)
+	if err := replaceDir(filepath.Join(extractDir, pkiDirName), filepath.Join(kubernetesDir, pkiDirName)); err != nil {
+		return errors.Wrap(err, "failed to restore the certificates")
+	}
+
+	for _, kf := range kubeconfig.KubeConfigFiles {
+		src := filepath.Join(extractDir, kubeconfigDirName, kf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, src); err != nil {
+			return err
+		} else if !exists {
+			continue
+		}
+		if err := copy.Copy(src, filepath.Join(kubernetesDir, kf)); err != nil {
+			return errors.Wrapf(err, "failed to restore the kubeconfig %s", kf)
+		}
+	}
+
+	for _, mf := range staticPodManifests {
+		if err := restoreManifest(filepath.Join(extractDir, manifestsDirName, mf), filepath.Join(kubernetesDir, manifestsDirName, mf)); err != nil {
+			return errors.Wrapf(err, "failed to restore the static pod manifest %s", mf)
+		}
+	}
+
+	src := filepath.Join(extractDir, kubeletPKIDirName)
+	if exists, err := path.Exists(path.CheckFollowSymlink, src); err != nil {
+		return err
+	} else if exists {
+		if err := replaceDir(src, kubeletPKIDir); err != nil {
+			return errors.Wrap(err, "failed to restore the kubelet certificates")
+		}
+	}
+	return nil
+}
+
+// restoreManifest writes the static pod manifest src to dest atomically, so the kubelet never reads a partial
+// manifest. It does nothing if the backup has no src, e.g. the backups taken by the old versions.
+func restoreManifest(src, dest string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(dest, b, info.Mode().Perm())
+}
+
+// verify checks the files extracted into the root directory match the manifest.
+func verify(m *Manifest, root string) error {
+	for _, f := range m.Files {
+		p := filepath.Join(root, filepath.FromSlash(f.Path))
+		if f.Symlink != "" {
+			link, err := os.Readlink(p)
+			if err != nil {
+				return errors.Wrapf(err, "failed to verify %s", f.Path)
+			}
+			if link != f.Symlink {
+				return errors.Errorf("the symlink %s points to %s, expected %s", f.Path, link, f.Symlink)
+			}
+			continue
+		}
+
+		sum, err := sha256File(p)
+		if err != nil {
+			return errors.Wrapf(err, "failed to verify %s", f.Path)
+		}
+		if sum != f.SHA256 {
+			return errors.Errorf("the checksum of %s is %s, expected %s", f.Path, sum, f.SHA256)
+		}
+	}
+	return nil
+}
+
+// replaceDir copies src to a temp directory next to dest and swaps it with dest,
+// so the files created after the backup are removed too.
+func replaceDir(src, dest string) error {
+	tmp := dest + ".certadm-restore"
+	old := dest + ".certadm-old"
+	os.RemoveAll(tmp)
+	os.RemoveAll(old)
+
+	if err := copy.Copy(src, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+
+	if exists, err := path.Exists(path.CheckFollowSymlink, dest); err != nil {
+		return err
+	} else if exists {
+		if err := os.Rename(dest, old); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Rename(old, dest)
+		return err
+	}
+	return os.RemoveAll(old)
+}
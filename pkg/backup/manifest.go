@@ -0,0 +1,140 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/kubeconfig"
+	"github.com/pytimer/certadm/pkg/util"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// ManifestAPIVersion is the apiVersion of the backup manifest
+	ManifestAPIVersion = "backup.certadm.pytimer.github.com/v1alpha1"
+	// ManifestKind is the kind of the backup manifest
+	ManifestKind = "BackupManifest"
+)
+
+// Manifest describes the content of a backup.
+type Manifest struct {
+	Kind       string `json:"kind" yaml:"kind"`
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+
+	Name           string    `json:"name" yaml:"name"`
+	CreatedAt      time.Time `json:"createdAt" yaml:"createdAt"`
+	CertadmVersion string    `json:"certadmVersion" yaml:"certadmVersion"`
+	KubeadmVersion string    `json:"kubeadmVersion,omitempty" yaml:"kubeadmVersion,omitempty"`
+	Hostname       string    `json:"hostname" yaml:"hostname"`
+	// KubernetesDir is the directory which the PKI directory, the kubeconfig files and the static pod manifests
+	// are backed up from
+	KubernetesDir string `json:"kubernetesDir" yaml:"kubernetesDir"`
+	// KubeletPKIDir is the directory which the kubelet certificates are backed up from
+	KubeletPKIDir string `json:"kubeletPKIDir" yaml:"kubeletPKIDir"`
+	// Files is the list of the files in the backup archive
+	Files []File `json:"files" yaml:"files"`
+}
+
+// File describes a file in the backup archive.
+type File struct {
+	// Path is the path in the backup archive, e.g. pki/apiserver.crt
+	Path string `json:"path" yaml:"path"`
+	// SHA256 is the checksum of the file content, it is empty for the symlink
+	SHA256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	// Symlink is the target of the symlink
+	Symlink string `json:"symlink,omitempty" yaml:"symlink,omitempty"`
+	// NotAfter is the expiration of the certificate in the file, it is empty if the file is not a certificate
+	NotAfter *time.Time `json:"notAfter,omitempty" yaml:"notAfter,omitempty"`
+}
+
+func loadManifest(filename string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if err := yaml.Unmarshal(b, m); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the backup manifest %s", filename)
+	}
+	if m.Kind != ManifestKind || m.APIVersion != ManifestAPIVersion {
+		return nil, errors.Errorf("%s is not a %s of %s", filename, ManifestKind, ManifestAPIVersion)
+	}
+	return m, nil
+}
+
+func writeManifest(filename string, m *Manifest) error {
+	b, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(filename, b, 0600)
+}
+
+// newFile returns the File of the p in the root directory.
+func newFile(root, p string) (File, error) {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return File{}, err
+	}
+	f := File{Path: filepath.ToSlash(rel)}
+
+	info, err := os.Lstat(p)
+	if err != nil {
+		return f, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		f.Symlink, err = os.Readlink(p)
+		return f, err
+	}
+
+	f.SHA256, err = sha256File(p)
+	if err != nil {
+		return f, err
+	}
+	f.NotAfter = certificateNotAfter(p)
+	return f, nil
+}
+
+func sha256File(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// certificateNotAfter returns the expiration of the certificate or the client certificate embedded
+// in the kubeconfig file, it returns nil if p does not contain a certificate.
+func certificateNotAfter(p string) *time.Time {
+	switch {
+	case strings.HasSuffix(p, ".crt"), strings.HasSuffix(p, ".pem"):
+		certs, err := pkiutil.CertsFromFile(p)
+		if err != nil {
+			return nil
+		}
+		return &certs[0].NotAfter
+	case strings.HasSuffix(p, ".conf"):
+		cert, err := kubeconfig.LoadClientCertificate(p)
+		if err != nil || cert == nil {
+			return nil
+		}
+		return &cert.NotAfter
+	}
+	return nil
+}
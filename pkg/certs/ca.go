@@ -0,0 +1,154 @@
+package certs
+
+import (
+	"crypto/x509"
+
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/util"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog"
+)
+
+// RegenerableCAs is the list of the CAs which can be regenerated by "certadm ca regenerate", an alias of RotateCA.
+// The cluster CA and the etcd CA are trusted by every component and kubeconfig, so they are rotated by "certadm ca rotate".
+var RegenerableCAs = []string{
+	constants.FrontProxyCACertAndKeyBaseName,
+}
+
+// RotatableCAs is the list of the CAs which can be rotated with an overlapping trust bundle.
+var RotatableCAs = []string{
+	constants.CACertAndKeyBaseName,
+	constants.EtcdCACertAndKeyBaseName,
+	constants.FrontProxyCACertAndKeyBaseName,
+}
+
+// RotateCA creates a new CA with the subject of the existing one, writes the trust bundle of the new and
+// the old CA certificates to the CA certificate file and re-issues the leaf certificates signed by the CA
+// with the new CA. The new CA certificate is the first one of the bundle, so it matches the CA key.
+// It returns the trust bundle.
+func RotateCA(certDir, caName string) ([]*x509.Certificate, error) {
+	if !isRotatableCA(caName) {
+		return nil, errors.Errorf("the %s CA can't be rotated, supported CAs: %v", caName, RotatableCAs)
+	}
+
+	bundle, err := pkiutil.TryLoadCertsFromDisk(certDir, caName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the %s CA", caName)
+	}
+	if len(bundle) > 1 {
+		return nil, errors.Errorf("the rotation of the %s CA is in progress, please finalize it by 'certadm ca rotate --finalize' first", caName)
+	}
+
+	key, err := pkiutil.NewPrivateKey()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create the %s private key", caName)
+	}
+	newCA, err := pkiutil.NewSelfSignedCACert(bundle[0], key, constants.CAValidity)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create the %s certificate", caName)
+	}
+	bundle = []*x509.Certificate{newCA, bundle[0]}
+	if err := pkiutil.WriteKey(certDir, caName, key); err != nil {
+		return nil, errors.Wrapf(err, "failed to write the %s private key", caName)
+	}
+	if err := pkiutil.WriteCerts(certDir, caName, bundle); err != nil {
+		return nil, errors.Wrapf(err, "failed to write the %s trust bundle", caName)
+	}
+	klog.Infof("[certs] Rotated %s certificate and key, expires on %s, the old CA is kept in the trust bundle", caName, newCA.NotAfter)
+
+	for _, c := range leafCertificates {
+		if c.CAName != caName {
+			continue
+		}
+		if err := RenewLeafCertificate(certDir, c, nil); err != nil {
+			return nil, err
+		}
+	}
+	return bundle, nil
+}
+
+// FinalizeCARotation drops the old CA certificates from the trust bundle of the CA, so only the CA certificate
+// matching the CA key is trusted. It returns the CA certificate, or nil if the rotation is not in progress.
+func FinalizeCARotation(certDir, caName string) (*x509.Certificate, error) {
+	if !isRotatableCA(caName) {
+		return nil, errors.Errorf("the %s CA can't be rotated, supported CAs: %v", caName, RotatableCAs)
+	}
+
+	bundle, err := pkiutil.TryLoadCertsFromDisk(certDir, caName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the %s CA", caName)
+	}
+	if len(bundle) == 1 {
+		return nil, nil
+	}
+
+	key, err := pkiutil.TryLoadKeyFromDisk(certDir, caName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the %s CA", caName)
+	}
+	if err := pkiutil.VerifyCertAndKey(bundle[0], key, bundle[0]); err != nil {
+		return nil, errors.Wrapf(err, "the first certificate of the %s trust bundle doesn't match the CA key", caName)
+	}
+	if err := pkiutil.WriteCert(certDir, caName, bundle[0]); err != nil {
+		return nil, errors.Wrapf(err, "failed to write the %s certificate", caName)
+	}
+	klog.Infof("[certs] Dropped %d old certificates from the %s trust bundle", len(bundle)-1, caName)
+	return bundle[0], nil
+}
+
+// SignedCertificates returns the names of the leaf certificates signed by the CA.
+func SignedCertificates(caName string) []string {
+	names := []string{}
+	for _, c := range leafCertificates {
+		if c.CAName == caName {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func isRotatableCA(caName string) bool {
+	for _, n := range RotatableCAs {
+		if n == caName {
+			return true
+		}
+	}
+	return false
+}
+
+// configMap is the minimal ConfigMap manifest written by certadm.
+type configMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   configMapMetadata `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type configMapMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+// WriteRequestHeaderCAConfigMap writes the kube-system/extension-apiserver-authentication ConfigMap
+// manifest with the requestheader-client-ca-file bundle of the front proxy CA certificates.
+func WriteRequestHeaderCAConfigMap(filename string, caCerts ...*x509.Certificate) error {
+	cm := &configMap{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata: configMapMetadata{
+			Name:      constants.ExtensionAPIServerAuthenticationConfigMap,
+			Namespace: constants.NamespaceSystem,
+		},
+		Data: map[string]string{
+			constants.RequestHeaderClientCAFileKey: string(pkiutil.EncodeCertsPEM(caCerts)),
+		},
+	}
+	b, err := yaml.Marshal(cm)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(filename, b, 0644)
+}
@@ -5,23 +5,30 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/pytimer/certadm/pkg/constants"
 	"github.com/pytimer/certadm/pkg/kubeadm"
 
-	"github.com/otiai10/copy"
 	"k8s.io/klog"
 	"k8s.io/utils/path"
-	"k8s.io/utils/temp"
 )
 
+// caCertificates is the list of the CA certificates and keys created by kubeadm,
+// they are preserved when renewing the leaf certificates.
+var caCertificates = []string{
+	"ca.crt",
+	"ca.key",
+	"etcd/ca.crt",
+	"etcd/ca.key",
+	"front-proxy-ca.crt",
+	"front-proxy-ca.key",
+}
+
+// defaultCertificates is the list of the leaf certificates and keys created by kubeadm, exclude CA and sa.
 var defaultCertificates = []string{
 	"apiserver.crt",
 	"apiserver.key",
 	"apiserver-kubelet-client.crt",
 	"apiserver-kubelet-client.key",
 	// Front Proxy certs
-	"front-proxy-ca.crt",
-	"front-proxy-ca.key",
 	"front-proxy-client.crt",
 	"front-proxy-client.key",
 	// etcd certs
@@ -35,34 +42,28 @@ var defaultCertificates = []string{
 	"apiserver-etcd-client.key",
 }
 
-// BackupCertificates ...
-func BackupCertificates(src, dest string) error {
-	if dest == "" {
-		dir, err := temp.CreateTempDir(constants.TempDirPrefix)
-		if err != nil {
-			dest = constants.DefaultTempCertificatesDir
-		} else {
-			dest = dir.Name
-		}
-	}
-
-	klog.V(2).Infof("[certs] Backup certificates from %s to %s \n", src, dest)
-	if err := copy.Copy(src, dest); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// RemoveOldCertificates remove unused certificates in certDir.
-func RemoveOldCertificates(certDir string) error {
+// OldCertificates returns the existing leaf certificates and keys in certDir, the CA certificates are excluded.
+func OldCertificates(certDir string) ([]string, error) {
+	files := []string{}
 	for _, cert := range defaultCertificates {
 		p := filepath.Join(certDir, cert)
 		if exists, err := path.Exists(path.CheckFollowSymlink, p); err != nil {
-			return err
+			return nil, err
 		} else if !exists {
 			continue
 		}
+		files = append(files, p)
+	}
+	return files, nil
+}
+
+// RemoveOldCertificates remove the leaf certificates in certDir, the CA certificates are preserved.
+func RemoveOldCertificates(certDir string) error {
+	files, err := OldCertificates(certDir)
+	if err != nil {
+		return err
+	}
+	for _, p := range files {
 		if err := os.Remove(p); err != nil {
 			return err
 		}
@@ -86,11 +87,39 @@ func RenewCertificate(conf string) error {
 	return nil
 }
 
-func RemoveKubeletCertificate(certDir string) error {
-	return filepath.Walk(certDir, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
+// KubeletCertificates returns the files in the kubelet certificates directory, the kubelet serving certificate
+// and key are excluded because they are renewed by RenewKubeletServingCertificate.
+func KubeletCertificates(certDir string) ([]string, error) {
+	files := []string{}
+	if exists, err := path.Exists(path.CheckFollowSymlink, certDir); err != nil {
+		return nil, err
+	} else if !exists {
+		return files, nil
+	}
+
+	err := filepath.Walk(certDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || path == KubeletServingCertificatePath(certDir) || path == filepath.Join(certDir, kubeletServingBaseName+".key") {
 			return nil
 		}
-		return os.Remove(path)
+		files = append(files, path)
+		return nil
 	})
+	return files, err
+}
+
+// RemoveKubeletCertificate removes the kubelet certificates returned by KubeletCertificates.
+func RemoveKubeletCertificate(certDir string) error {
+	files, err := KubeletCertificates(certDir)
+	if err != nil {
+		return err
+	}
+	for _, p := range files {
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+	}
+	return nil
 }
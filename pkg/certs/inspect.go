@@ -0,0 +1,73 @@
+package certs
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+// CertificateInfo describes a certificate found on the node.
+type CertificateInfo struct {
+	Name         string
+	Path         string
+	Subject      string
+	Issuer       string
+	NotAfter     time.Time
+	ResidualDays int
+	IsCA         bool
+}
+
+// NewCertificateInfo returns the CertificateInfo of the cert.
+func NewCertificateInfo(name, p string, cert *x509.Certificate) *CertificateInfo {
+	return &CertificateInfo{
+		Name:         name,
+		Path:         p,
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		NotAfter:     cert.NotAfter,
+		ResidualDays: residualDays(cert.NotAfter),
+		IsCA:         cert.IsCA,
+	}
+}
+
+func residualDays(notAfter time.Time) int {
+	return int(time.Until(notAfter).Hours() / 24)
+}
+
+// ListCertificates returns the CA and leaf certificates in the certDir.
+// The certificate which not exists will be skipped.
+func ListCertificates(certDir string) ([]*CertificateInfo, error) {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, f := range append(append([]string{}, caCertificates...), defaultCertificates...) {
+		if !strings.HasSuffix(f, ".crt") || seen[f] {
+			continue
+		}
+		seen[f] = true
+		names = append(names, strings.TrimSuffix(f, ".crt"))
+	}
+
+	infos := []*CertificateInfo{}
+	for _, name := range names {
+		p := filepath.Join(certDir, name+".crt")
+		if exists, err := path.Exists(path.CheckFollowSymlink, p); err != nil {
+			return nil, err
+		} else if !exists {
+			klog.Warningf("[certs] certificate %s not exists, skip it", p)
+			continue
+		}
+
+		cert, err := pkiutil.TryLoadCertFromDisk(certDir, name)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, NewCertificateInfo(name, p, cert))
+	}
+	return infos, nil
+}
@@ -0,0 +1,315 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/util"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+const (
+	// kubeletCurrentSuffix is the suffix of the symlink to the current certificate and key of the kubelet
+	// certificate rotation, e.g. kubelet-client-current.pem
+	kubeletCurrentSuffix = "-current.pem"
+	// kubeletUpdatedSuffix is the suffix of the temporary symlink which replaces the current symlink
+	kubeletUpdatedSuffix = "-updated.pem"
+	// kubeletTimestampLayout is the timestamp layout in the file names of the kubelet certificate rotation,
+	// e.g. kubelet-client-2020-05-29-18-24-11.pem
+	kubeletTimestampLayout = "2006-01-02-15-04-05"
+)
+
+// RenewKubeletClientCertificate re-issues the kubelet client certificate in the certFile referred by kubelet.conf,
+// e.g. /var/lib/kubelet/pki/kubelet-client-current.pem, with the cluster CA in the caCertDir and returns the path of
+// the new file. If the certFile is the current symlink of the kubelet certificate rotation, the certificate and key
+// are written to a new kubelet-client-<timestamp>.pem and the symlink is switched to it, the same as the kubelet
+// does, so the old files are kept. Otherwise the certFile is rewritten.
+func RenewKubeletClientCertificate(certFile, caCertDir string, o *RenewOptions) (string, error) {
+	if o == nil {
+		o = &RenewOptions{}
+	}
+
+	data, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load the kubelet client certificate")
+	}
+	cs, err := pkiutil.ParseCertsPEM(data)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the kubelet client certificate %s", certFile)
+	}
+
+	caCert, caKey, err := pkiutil.TryLoadCertAndKeyFromDisk(caCertDir, constants.CACertAndKeyBaseName)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load the cluster CA")
+	}
+
+	var key crypto.Signer
+	if o.RotateKeys {
+		key, err = pkiutil.NewPrivateKey()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to create the kubelet client key")
+		}
+	} else {
+		key, err = pkiutil.ParsePrivateKeyPEM(data)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse the kubelet client key %s", certFile)
+		}
+	}
+	validity := o.Validity.For("kubelet.conf")
+	cert, err := pkiutil.RenewSignedCert(cs[0], key, caCert, caKey, validity)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign the kubelet client certificate")
+	}
+	WarnIfCapped("kubelet.conf", cert, caCert, validity)
+	keyPEM, err := pkiutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return "", err
+	}
+	pemData := append(pkiutil.EncodeCertPEM(cert), keyPEM...)
+
+	base := filepath.Base(certFile)
+	if !strings.HasSuffix(base, kubeletCurrentSuffix) {
+		if err := util.WriteFileAtomic(certFile, pemData, 0600); err != nil {
+			return "", err
+		}
+		klog.Infof("[certs] Renewed the kubelet client certificate %s, expires on %s", certFile, cert.NotAfter)
+		return certFile, nil
+	}
+
+	dir := filepath.Dir(certFile)
+	prefix := strings.TrimSuffix(base, kubeletCurrentSuffix)
+	newFile := filepath.Join(dir, fmt.Sprintf("%s-%s.pem", prefix, time.Now().Format(kubeletTimestampLayout)))
+	if err := util.WriteFileAtomic(newFile, pemData, 0600); err != nil {
+		return "", err
+	}
+	updated := filepath.Join(dir, prefix+kubeletUpdatedSuffix)
+	if err := os.Remove(updated); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if err := os.Symlink(newFile, updated); err != nil {
+		return "", errors.Wrapf(err, "failed to create the symlink %s", updated)
+	}
+	if err := os.Rename(updated, certFile); err != nil {
+		return "", errors.Wrapf(err, "failed to switch the symlink %s to %s", certFile, newFile)
+	}
+	klog.Infof("[certs] Renewed the kubelet client certificate %s -> %s, expires on %s", certFile, newFile, cert.NotAfter)
+	return newFile, nil
+}
+
+// KubeletServingName is the name of the kubelet serving certificate in the renewal targets and the validity config.
+const KubeletServingName = "kubelet-serving"
+
+// NodeUserPrefix is the prefix of the common name of the certificates of the nodes, e.g. the kubelet client certificate.
+const NodeUserPrefix = "system:node:"
+
+// The modes of the kubelet serving certificate.
+const (
+	// KubeletServingSelfSigned is the self-signed serving certificate, which the kubelet creates by default
+	KubeletServingSelfSigned = "self-signed"
+	// KubeletServingCASigned is the serving certificate signed by the cluster CA
+	KubeletServingCASigned = "ca"
+)
+
+const (
+	// kubeletServingBaseName is the base name of the kubelet serving certificate and key in the kubelet certificates directory
+	kubeletServingBaseName = "kubelet"
+	// kubeletServerCurrentFile is the current serving certificate of the kubelet certificate rotation, the kubelet
+	// uses it instead of kubelet.crt if serverTLSBootstrap is enabled
+	kubeletServerCurrentFile = "kubelet-server" + kubeletCurrentSuffix
+	// nodesGroup is the group of the nodes
+	nodesGroup = "system:nodes"
+)
+
+// KubeletServingOptions holds the options used to renew the kubelet serving certificate.
+type KubeletServingOptions struct {
+	// Mode is self-signed or ca, defaults to ca if the cluster CA key exists, or self-signed otherwise
+	Mode string
+	// SANs is the node name and the node IPs, defaults to the SANs of the existing certificate or the host name.
+	// The first DNS name is the node name.
+	SANs []string
+	// RotateKeys generates a new private key instead of reusing the existing one.
+	RotateKeys bool
+	// Validity is the validity period of the renewed certificate.
+	Validity *Validity
+}
+
+// KubeletServingCertificatePath returns the path of the kubelet serving certificate in the kubeletCertDir.
+func KubeletServingCertificatePath(kubeletCertDir string) string {
+	return filepath.Join(kubeletCertDir, kubeletServingBaseName+".crt")
+}
+
+// KubeletServingCertificateInfo returns the CertificateInfo of the kubelet serving certificate in the kubeletCertDir,
+// or nil if it not exists.
+func KubeletServingCertificateInfo(kubeletCertDir string) (*CertificateInfo, error) {
+	p := KubeletServingCertificatePath(kubeletCertDir)
+	if exists, err := path.Exists(path.CheckFollowSymlink, p); err != nil || !exists {
+		return nil, err
+	}
+	cs, err := pkiutil.CertsFromFile(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the kubelet serving certificate")
+	}
+	return NewCertificateInfo(KubeletServingName, p, cs[0]), nil
+}
+
+// RenewKubeletServingCertificate re-issues the kubelet serving certificate kubelet.crt in the kubeletCertDir, either
+// self-signed or signed by the cluster CA in the caCertDir, it is signed by the cluster CA by default if the CA key
+// exists. The certificate is created if it not exists and the mode is set, and is skipped if the kubelet uses the serving certificate of the kubelet certificate rotation.
+func RenewKubeletServingCertificate(kubeletCertDir, caCertDir string, o *KubeletServingOptions) error {
+	if o == nil {
+		o = &KubeletServingOptions{}
+	}
+	if exists, err := path.Exists(path.CheckFollowSymlink, filepath.Join(kubeletCertDir, kubeletServerCurrentFile)); err != nil {
+		return err
+	} else if exists {
+		klog.Warningf("[certs] the kubelet rotates its serving certificate %s, skip %s", kubeletServerCurrentFile, KubeletServingCertificatePath(kubeletCertDir))
+		return nil
+	}
+
+	var oldCert *x509.Certificate
+	var key crypto.Signer
+	if exists, err := path.Exists(path.CheckFollowSymlink, KubeletServingCertificatePath(kubeletCertDir)); err != nil {
+		return err
+	} else if exists {
+		oldCert, key, err = pkiutil.TryLoadCertAndKeyFromDisk(kubeletCertDir, kubeletServingBaseName)
+		if err != nil {
+			return errors.Wrap(err, "failed to load the kubelet serving certificate")
+		}
+	} else if o.Mode == "" {
+		klog.Infof("[certs] the kubelet serving certificate %s not exists, the kubelet creates it", KubeletServingCertificatePath(kubeletCertDir))
+		return nil
+	}
+	if key == nil || o.RotateKeys {
+		var err error
+		key, err = pkiutil.NewPrivateKey()
+		if err != nil {
+			return errors.Wrap(err, "failed to create the kubelet serving key")
+		}
+	}
+
+	var caCert *x509.Certificate
+	var caKey crypto.Signer
+	mode := o.Mode
+	if mode == "" || mode == KubeletServingCASigned {
+		var err error
+		caCert, caKey, err = pkiutil.TryLoadCertAndKeyFromDisk(caCertDir, constants.CACertAndKeyBaseName)
+		if err != nil {
+			if mode == KubeletServingCASigned {
+				return errors.Wrap(err, "failed to load the cluster CA")
+			}
+			klog.V(1).Infof("[certs] failed to load the cluster CA, the kubelet serving certificate is self-signed: %v", err)
+			caCert = nil
+		}
+	}
+	if mode == "" {
+		// the clients can verify the serving certificate signed by the cluster CA, so it is preferred
+		// on the nodes with the CA key, e.g. the control plane nodes.
+		mode = KubeletServingCASigned
+		if caCert == nil {
+			mode = KubeletServingSelfSigned
+		}
+	}
+
+	sans, err := kubeletServingSANs(oldCert, o.SANs)
+	if err != nil {
+		return err
+	}
+	tmpl := &x509.Certificate{
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	setCertificateSANs(tmpl, sans)
+	nodeName := tmpl.DNSNames[0]
+
+	validity := o.Validity.For(KubeletServingName)
+	var cert *x509.Certificate
+	switch mode {
+	case KubeletServingSelfSigned:
+		// the same common name as the serving certificate created by the kubelet
+		tmpl.Subject = pkix.Name{CommonName: fmt.Sprintf("%s@%d", nodeName, time.Now().Unix())}
+		cert, err = pkiutil.NewSelfSignedCert(tmpl, key, validity)
+	case KubeletServingCASigned:
+		// the same subject as the serving certificate requested by the kubelet with serverTLSBootstrap
+		tmpl.Subject = pkix.Name{CommonName: NodeUserPrefix + nodeName, Organization: []string{nodesGroup}}
+		cert, err = pkiutil.RenewSignedCert(tmpl, key, caCert, caKey, validity)
+		if err == nil {
+			WarnIfCapped(KubeletServingName, cert, caCert, validity)
+		}
+	default:
+		return errors.Errorf("unknown kubelet serving certificate mode %q, one of: %s|%s", mode, KubeletServingSelfSigned, KubeletServingCASigned)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to sign the kubelet serving certificate")
+	}
+
+	if err := pkiutil.WriteCertAndKey(kubeletCertDir, kubeletServingBaseName, cert, key); err != nil {
+		return err
+	}
+	klog.Infof("[certs] Renewed the kubelet serving certificate %s (%s) for %v, expires on %s",
+		KubeletServingCertificatePath(kubeletCertDir), mode, sans, cert.NotAfter)
+	return nil
+}
+
+// KubeletServingCertificateCASigned returns true if the kubelet serving certificate kubelet.crt in the kubeletCertDir
+// exists, is used by the kubelet and is signed by the cluster CA in the caCertDir, e.g. it is renewed by
+// '--kubelet-serving-mode=ca'.
+func KubeletServingCertificateCASigned(kubeletCertDir, caCertDir string) (bool, error) {
+	if exists, err := path.Exists(path.CheckFollowSymlink, filepath.Join(kubeletCertDir, kubeletServerCurrentFile)); err != nil || exists {
+		return false, err
+	}
+	if exists, err := path.Exists(path.CheckFollowSymlink, KubeletServingCertificatePath(kubeletCertDir)); err != nil || !exists {
+		return false, err
+	}
+	cert, err := pkiutil.TryLoadCertFromDisk(kubeletCertDir, kubeletServingBaseName)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to load the kubelet serving certificate")
+	}
+	return signedByClusterCA(cert, caCertDir)
+}
+
+// signedByClusterCA returns true if the cert is signed by any certificate of the cluster CA trust bundle in the
+// caCertDir, the trust bundle has the old CA certificate during the CA rotation.
+func signedByClusterCA(cert *x509.Certificate, caCertDir string) (bool, error) {
+	caCerts, err := pkiutil.TryLoadCertsFromDisk(caCertDir, constants.CACertAndKeyBaseName)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to load the cluster CA")
+	}
+	for _, c := range caCerts {
+		if cert.CheckSignatureFrom(c) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// kubeletServingSANs returns the SANs of the kubelet serving certificate, the first one is the node name.
+// It defaults to the SANs of the old certificate, or the host name if the old certificate has no DNS name.
+func kubeletServingSANs(oldCert *x509.Certificate, sans []string) ([]string, error) {
+	if len(sans) == 0 && oldCert != nil {
+		sans = CertificateSANs(oldCert)
+	}
+	for _, san := range sans {
+		if net.ParseIP(san) == nil {
+			return sans, nil
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the node name, please set it in the SANs")
+	}
+	return append([]string{strings.ToLower(hostname)}, sans...), nil
+}
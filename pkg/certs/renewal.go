@@ -0,0 +1,214 @@
+package certs
+
+import (
+	"path/filepath"
+
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+// Certificate describes a leaf certificate created by kubeadm and the CA which signs it.
+type Certificate struct {
+	// Name is the name used by `kubeadm certs renew`
+	Name string
+	// BaseName is the file name of the certificate and key without the extension
+	BaseName string
+	// CAName is the base name of the CA which signs the certificate
+	CAName string
+}
+
+// leafCertificates is the list of the leaf certificates which can be renewed.
+var leafCertificates = []*Certificate{
+	{Name: "apiserver", BaseName: "apiserver", CAName: constants.CACertAndKeyBaseName},
+	{Name: "apiserver-kubelet-client", BaseName: "apiserver-kubelet-client", CAName: constants.CACertAndKeyBaseName},
+	{Name: "front-proxy-client", BaseName: "front-proxy-client", CAName: constants.FrontProxyCACertAndKeyBaseName},
+	{Name: "etcd-server", BaseName: "etcd/server", CAName: constants.EtcdCACertAndKeyBaseName},
+	{Name: "etcd-peer", BaseName: "etcd/peer", CAName: constants.EtcdCACertAndKeyBaseName},
+	{Name: "etcd-healthcheck-client", BaseName: "etcd/healthcheck-client", CAName: constants.EtcdCACertAndKeyBaseName},
+	{Name: "apiserver-etcd-client", BaseName: "apiserver-etcd-client", CAName: constants.EtcdCACertAndKeyBaseName},
+}
+
+// LeafCertificates returns the leaf certificates which can be renewed.
+func LeafCertificates() []*Certificate {
+	return leafCertificates
+}
+
+// leafCertificate returns the leaf certificate by the name, or nil if the name is unknown.
+func leafCertificate(name string) *Certificate {
+	for _, c := range leafCertificates {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// RenewOptions holds the options used to renew the certificates in-process.
+type RenewOptions struct {
+	// RotateKeys generates new private keys instead of reusing the existing ones.
+	RotateKeys bool
+	// SANChanges is the SANs added to or removed from the renewed certificates.
+	SANChanges *SANChanges
+	// Validity is the validity period of the renewed certificates.
+	Validity *Validity
+	// Names is the names of the certificates and kubeconfig files to renew, e.g. apiserver or admin.conf,
+	// all of them are renewed if it is empty.
+	Names []string
+}
+
+// Selected returns true if the certificate or kubeconfig file is in the names, or the names is empty.
+func Selected(names []string, name string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	return sets.NewString(names...).Has(name)
+}
+
+// existingLeafCertificates returns the leaf certificates in the names, or all of them if the names is empty,
+// whose files and CA files, and the CA keys if withCAKey is true, exist in the certDir. The missing ones are
+// skipped, e.g. the etcd certificates of a cluster with external etcd, unless they are in the names.
+func existingLeafCertificates(certDir string, names []string, withCAKey bool) ([]*Certificate, error) {
+	existing := []*Certificate{}
+	for _, c := range leafCertificates {
+		if !Selected(names, c.Name) {
+			continue
+		}
+		files := []string{c.BaseName + ".crt", c.BaseName + ".key", c.CAName + ".crt"}
+		if withCAKey {
+			files = append(files, c.CAName+".key")
+		}
+		missing := ""
+		for _, f := range files {
+			exists, err := path.Exists(path.CheckFollowSymlink, filepath.Join(certDir, f))
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				missing = filepath.Join(certDir, f)
+				break
+			}
+		}
+		if missing == "" {
+			existing = append(existing, c)
+			continue
+		}
+		if len(names) > 0 {
+			return nil, errors.Errorf("the %s certificate is requested, but %s not exists", c.Name, missing)
+		}
+		klog.V(1).Infof("[certs] %s not exists, skip the %s certificate", missing, c.Name)
+	}
+	return existing, nil
+}
+
+// RenewLeafCertificates renews the leaf certificates selected by the RenewOptions in the certDir in-process.
+// The certificates which don't exist, or whose CA doesn't exist, are skipped unless they are named explicitly.
+func RenewLeafCertificates(certDir string, o *RenewOptions) error {
+	if o == nil {
+		o = &RenewOptions{}
+	}
+	leaves, err := existingLeafCertificates(certDir, o.Names, true)
+	if err != nil {
+		return err
+	}
+	for _, c := range leaves {
+		if err := RenewLeafCertificate(certDir, c, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LeafCertificateFiles returns the certificates, and the keys if RotateKeys is true, which are written
+// by RenewLeafCertificates.
+func LeafCertificateFiles(certDir string, o *RenewOptions) ([]string, error) {
+	if o == nil {
+		o = &RenewOptions{}
+	}
+	leaves, err := existingLeafCertificates(certDir, o.Names, true)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, c := range leaves {
+		files = append(files, filepath.Join(certDir, c.BaseName+".crt"))
+		if o.RotateKeys {
+			files = append(files, filepath.Join(certDir, c.BaseName+".key"))
+		}
+	}
+	return files, nil
+}
+
+// RenewLeafCertificate reads the existing certificate and signs a new one with the CA,
+// the subject, SANs, key usages and extended key usages are preserved, except the SAN changes.
+// The existing private key is reused unless the RotateKeys is true.
+func RenewLeafCertificate(certDir string, c *Certificate, o *RenewOptions) error {
+	if o == nil {
+		o = &RenewOptions{}
+	}
+
+	cert, key, err := pkiutil.TryLoadCertAndKeyFromDisk(certDir, c.BaseName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the %s certificate", c.Name)
+	}
+	if !o.SANChanges.Empty() {
+		setCertificateSANs(cert, o.SANChanges.Apply(c.Name, CertificateSANs(cert)))
+	}
+
+	caCert, caKey, err := pkiutil.TryLoadCertAndKeyFromDisk(certDir, c.CAName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the %s CA", c.CAName)
+	}
+
+	if o.RotateKeys {
+		key, err = pkiutil.NewPrivateKey()
+		if err != nil {
+			return errors.Wrapf(err, "failed to create the %s private key", c.Name)
+		}
+	}
+
+	validity := o.Validity.For(c.Name)
+	newCert, err := pkiutil.RenewSignedCert(cert, key, caCert, caKey, validity)
+	if err != nil {
+		return errors.Wrapf(err, "failed to sign the %s certificate", c.Name)
+	}
+	WarnIfCapped(c.Name, newCert, caCert, validity)
+
+	if o.RotateKeys {
+		err = pkiutil.WriteCertAndKey(certDir, c.BaseName, newCert, key)
+	} else {
+		err = pkiutil.WriteCert(certDir, c.BaseName, newCert)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to write the %s certificate", c.Name)
+	}
+	klog.Infof("[certs] Renewed %s certificate, expires on %s", c.Name, newCert.NotAfter)
+	return nil
+}
+
+// ValidateLeafCertificates checks the leaf certificates in the names, or all of them if the names is empty,
+// match the private keys and are signed by the CA. The missing certificates are skipped unless they are in the names.
+func ValidateLeafCertificates(certDir string, names []string) error {
+	leaves, err := existingLeafCertificates(certDir, names, false)
+	if err != nil {
+		return err
+	}
+	for _, c := range leaves {
+		cert, key, err := pkiutil.TryLoadCertAndKeyFromDisk(certDir, c.BaseName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load the %s certificate", c.Name)
+		}
+		caCert, err := pkiutil.TryLoadCertFromDisk(certDir, c.CAName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load the %s CA", c.CAName)
+		}
+		if err := pkiutil.VerifyCertAndKey(cert, key, caCert); err != nil {
+			return errors.Wrapf(err, "the %s certificate is invalid", c.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package certs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/util"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+// ServiceAccountOldPublicKeyPath returns the path of the service account public key replaced by the key rotation.
+func ServiceAccountOldPublicKeyPath(certDir string) string {
+	return filepath.Join(certDir, constants.ServiceAccountOldPublicKeyBaseName+".pub")
+}
+
+// RotateServiceAccountKey moves the service account public key to sa-old.pub and generates a new key pair,
+// so the old public key can still verify the tokens signed by the old private key.
+func RotateServiceAccountKey(certDir string) error {
+	oldPublicKeyPath := ServiceAccountOldPublicKeyPath(certDir)
+	if exists, err := path.Exists(path.CheckFollowSymlink, oldPublicKeyPath); err != nil {
+		return err
+	} else if exists {
+		return errors.Errorf("the rotation of the service account key is in progress, please finalize it by 'certadm sa rotate --finalize' first")
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(certDir, constants.ServiceAccountKeyBaseName+".pub"))
+	if err != nil {
+		return errors.Wrap(err, "failed to load the service account public key")
+	}
+	if err := util.WriteFileAtomic(oldPublicKeyPath, b, 0600); err != nil {
+		return errors.Wrap(err, "failed to write the old service account public key")
+	}
+
+	key, err := pkiutil.NewPrivateKey()
+	if err != nil {
+		return errors.Wrap(err, "failed to create the service account private key")
+	}
+	if err := pkiutil.WriteKey(certDir, constants.ServiceAccountKeyBaseName, key); err != nil {
+		return errors.Wrap(err, "failed to write the service account private key")
+	}
+	if err := pkiutil.WritePublicKey(certDir, constants.ServiceAccountKeyBaseName, key.Public()); err != nil {
+		return errors.Wrap(err, "failed to write the service account public key")
+	}
+	klog.Infof("[certs] Rotated the service account key, the old public key is kept in %s", oldPublicKeyPath)
+	return nil
+}
+
+// FinalizeServiceAccountKeyRotation removes the old service account public key, it returns false if
+// the old public key not exists.
+func FinalizeServiceAccountKeyRotation(certDir string) (bool, error) {
+	oldPublicKeyPath := ServiceAccountOldPublicKeyPath(certDir)
+	if exists, err := path.Exists(path.CheckFollowSymlink, oldPublicKeyPath); err != nil {
+		return false, err
+	} else if !exists {
+		return false, nil
+	}
+
+	if err := os.Remove(oldPublicKeyPath); err != nil {
+		return false, err
+	}
+	klog.Infof("[certs] Removed the old service account public key %s", oldPublicKeyPath)
+	return true, nil
+}
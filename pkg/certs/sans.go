@@ -0,0 +1,196 @@
+package certs
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/path"
+)
+
+// SANCertificates is the names of the certificates which SANs are checked before the renewal.
+var SANCertificates = []string{"apiserver", "etcd-server", "etcd-peer"}
+
+// SANChanges is the SANs intentionally added to or removed from the certificates by the certificate name.
+type SANChanges struct {
+	Add    map[string][]string
+	Remove map[string][]string
+}
+
+// ParseSANChanges parses the values of '--add-san' and '--remove-san', the value format is [<certificate>=]<SAN>,
+// the certificate is one of SANCertificates and defaults to apiserver.
+func ParseSANChanges(add, remove []string) (*SANChanges, error) {
+	changes := &SANChanges{Add: map[string][]string{}, Remove: map[string][]string{}}
+	for _, v := range add {
+		name, san, err := parseSANChange(v)
+		if err != nil {
+			return nil, err
+		}
+		changes.Add[name] = append(changes.Add[name], san)
+	}
+	for _, v := range remove {
+		name, san, err := parseSANChange(v)
+		if err != nil {
+			return nil, err
+		}
+		changes.Remove[name] = append(changes.Remove[name], san)
+	}
+	return changes, nil
+}
+
+func parseSANChange(v string) (string, string, error) {
+	name, san := "apiserver", v
+	if kv := strings.SplitN(v, "=", 2); len(kv) == 2 {
+		name, san = kv[0], kv[1]
+	}
+	if !sets.NewString(SANCertificates...).Has(name) {
+		return "", "", errors.Errorf("invalid SAN %q, the certificate must be one of: %s", v, strings.Join(SANCertificates, "|"))
+	}
+	if san == "" {
+		return "", "", errors.Errorf("invalid SAN %q, the SAN is empty", v)
+	}
+	return name, CanonicalSAN(san), nil
+}
+
+// Empty returns true if there is no SAN change.
+func (s *SANChanges) Empty() bool {
+	return s == nil || (len(s.Add) == 0 && len(s.Remove) == 0)
+}
+
+// Apply returns the SANs of the certificate after the changes.
+func (s *SANChanges) Apply(name string, sans []string) []string {
+	if s == nil {
+		return sans
+	}
+	removed := sets.NewString(s.Remove[name]...)
+	seen := sets.NewString()
+	result := []string{}
+	for _, san := range append(append([]string{}, sans...), s.Add[name]...) {
+		if removed.Has(san) || seen.Has(san) {
+			continue
+		}
+		seen.Insert(san)
+		result = append(result, san)
+	}
+	return result
+}
+
+// SANDiff is the SANs of the existing certificate and the renewed certificate.
+type SANDiff struct {
+	Name string
+	Old  []string
+	New  []string
+}
+
+// Added returns the SANs of the renewed certificate which are not in the existing certificate.
+func (d *SANDiff) Added() []string {
+	return sets.NewString(d.New...).Difference(sets.NewString(d.Old...)).List()
+}
+
+// Removed returns the SANs of the existing certificate which are lost in the renewed certificate.
+func (d *SANDiff) Removed() []string {
+	return sets.NewString(d.Old...).Difference(sets.NewString(d.New...)).List()
+}
+
+// String returns the added and removed SANs, e.g. "+a.example.com -10.0.0.1".
+func (d *SANDiff) String() string {
+	changes := []string{}
+	for _, san := range d.Added() {
+		changes = append(changes, "+"+san)
+	}
+	for _, san := range d.Removed() {
+		changes = append(changes, "-"+san)
+	}
+	if len(changes) == 0 {
+		return "unchanged"
+	}
+	return strings.Join(changes, " ")
+}
+
+// ValidateSANDiffs returns an error if the renewal loses the SANs which are not removed intentionally,
+// or the intentional changes are not applied by the renewal.
+func ValidateSANDiffs(diffs []*SANDiff, changes *SANChanges) error {
+	if changes == nil {
+		changes = &SANChanges{}
+	}
+
+	errs := []string{}
+	checked := sets.NewString()
+	for _, d := range diffs {
+		checked.Insert(d.Name)
+		newSANs := sets.NewString(d.New...)
+		lost := sets.NewString(d.Removed()...).Difference(sets.NewString(changes.Remove[d.Name]...))
+		if lost.Len() > 0 {
+			errs = append(errs, fmt.Sprintf("the %s certificate would lose the SANs %v, use '--remove-san' to remove them intentionally", d.Name, lost.List()))
+		}
+		if missing := sets.NewString(changes.Add[d.Name]...).Difference(newSANs); missing.Len() > 0 {
+			errs = append(errs, fmt.Sprintf("the SANs %v can't be added to the %s certificate by the renewal", missing.List(), d.Name))
+		}
+		if kept := sets.NewString(changes.Remove[d.Name]...).Intersection(newSANs); kept.Len() > 0 {
+			errs = append(errs, fmt.Sprintf("the SANs %v can't be removed from the %s certificate by the renewal", kept.List(), d.Name))
+		}
+	}
+	for _, name := range SANCertificates {
+		if !checked.Has(name) && (len(changes.Add[name]) > 0 || len(changes.Remove[name]) > 0) {
+			errs = append(errs, fmt.Sprintf("the %s certificate not exists, can't change its SANs", name))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// CertificateSANs returns the DNS names and the IP addresses of the certificate.
+func CertificateSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
+
+// LoadCertificateSANs returns the SANs of the certificate by the name, it returns nil if the certificate not exists.
+func LoadCertificateSANs(certDir, name string) ([]string, error) {
+	c := leafCertificate(name)
+	if c == nil {
+		return nil, errors.Errorf("unknown certificate %s", name)
+	}
+	if exists, err := path.Exists(path.CheckFollowSymlink, filepath.Join(certDir, c.BaseName+".crt")); err != nil || !exists {
+		return nil, err
+	}
+
+	cert, err := pkiutil.TryLoadCertFromDisk(certDir, c.BaseName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the %s certificate", name)
+	}
+	return CertificateSANs(cert), nil
+}
+
+// setCertificateSANs replaces the DNS names and the IP addresses of the certificate.
+func setCertificateSANs(cert *x509.Certificate, sans []string) {
+	cert.DNSNames = nil
+	cert.IPAddresses = nil
+	for _, san := range sans {
+		if ip := net.ParseIP(san); ip != nil {
+			cert.IPAddresses = append(cert.IPAddresses, ip)
+		} else {
+			cert.DNSNames = append(cert.DNSNames, san)
+		}
+	}
+}
+
+// CanonicalSAN returns the canonical form of the IP address, or the name itself if it is not an IP address.
+func CanonicalSAN(san string) string {
+	if ip := net.ParseIP(san); ip != nil {
+		return ip.String()
+	}
+	return san
+}
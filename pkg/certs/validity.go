@@ -0,0 +1,42 @@
+package certs
+
+import (
+	"crypto/x509"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/constants"
+
+	"k8s.io/klog"
+)
+
+// Validity is the validity period of the renewed certificates.
+type Validity struct {
+	// Default is the validity of the certificates not in Certificates
+	Default time.Duration
+	// Certificates is the validity by the certificate name, e.g. apiserver or admin.conf
+	Certificates map[string]time.Duration
+}
+
+// For returns the validity of the certificate by the name, the default validity of certadm
+// is used if the Validity is nil.
+func (v *Validity) For(name string) time.Duration {
+	if v == nil {
+		return constants.CertificateValidity
+	}
+	if d, ok := v.Certificates[name]; ok {
+		return d
+	}
+	if v.Default > 0 {
+		return v.Default
+	}
+	return constants.CertificateValidity
+}
+
+// WarnIfCapped warns that the validity of the certificate is capped at the NotAfter of the CA,
+// the renewed certificate can't outlive the CA which signs it.
+func WarnIfCapped(name string, cert, caCert *x509.Certificate, validity time.Duration) {
+	if cert.NotAfter.Equal(caCert.NotAfter) && cert.NotAfter.Before(cert.NotBefore.Add(validity)) {
+		klog.Warningf("[certs] the validity %s of the %s certificate exceeds the expiration of the CA %s, it expires on %s with the CA, renew the CA to extend it",
+			validity, name, caCert.Subject.CommonName, caCert.NotAfter)
+	}
+}
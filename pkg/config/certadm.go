@@ -0,0 +1,88 @@
+package config
+
+import (
+	"io/ioutil"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/config/v1alpha1"
+	"github.com/pytimer/certadm/pkg/kubeconfig"
+	"github.com/pytimer/certadm/pkg/util"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// LoadCertadmConfigFile loads the CertadmConfiguration from the file.
+func LoadCertadmConfigFile(f string) (*v1alpha1.CertadmConfiguration, error) {
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &v1alpha1.CertadmConfiguration{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the certadm config %s", f)
+	}
+	if c.APIVersion != v1alpha1.SchemeGroupVersion || c.Kind != v1alpha1.CertadmConfigurationKind {
+		return nil, errors.Errorf("the certadm config %s must be the %s of %s", f, v1alpha1.CertadmConfigurationKind, v1alpha1.SchemeGroupVersion)
+	}
+	return c, nil
+}
+
+// NewValidity returns the validity period of the renewed certificates from the certadm config and
+// the '--validity' flag, the validity of the certificate in the config takes precedence over the flag,
+// and the flag takes precedence over the validity of the config. It returns nil if neither is set.
+func NewValidity(c *v1alpha1.CertadmConfiguration, validity string) (*certs.Validity, error) {
+	if c == nil {
+		c = &v1alpha1.CertadmConfiguration{}
+	}
+	if validity == "" {
+		validity = c.Validity
+	}
+	if validity == "" && len(c.Certificates) == 0 {
+		return nil, nil
+	}
+
+	v := &certs.Validity{Certificates: map[string]time.Duration{}}
+	if validity != "" {
+		d, err := ParseValidity(validity)
+		if err != nil {
+			return nil, err
+		}
+		v.Default = d
+	}
+
+	names := sets.NewString(kubeconfig.KubeConfigFiles...)
+	names.Insert(certs.KubeletServingName)
+	for _, c := range certs.LeafCertificates() {
+		names.Insert(c.Name)
+	}
+	for name, cc := range c.Certificates {
+		if !names.Has(name) {
+			return nil, errors.Errorf("unknown certificate %q in the certadm config, supported certificates: %v", name, names.List())
+		}
+		if cc.Validity == "" {
+			continue
+		}
+		d, err := ParseValidity(cc.Validity)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid validity of the certificate %s", name)
+		}
+		v.Certificates[name] = d
+	}
+	return v, nil
+}
+
+// ParseValidity parses the validity period of a certificate by util.ParseDuration, it must be positive.
+func ParseValidity(s string) (time.Duration, error) {
+	d, err := util.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.Errorf("invalid validity %q, it must be positive", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import "github.com/pytimer/certadm/pkg/kubeadm"
+
+// Merge sets the fields of the Config which are not set from the defaults.
+func Merge(c, defaults *kubeadm.Config) {
+	if c.KubernetesVersion == "" {
+		c.KubernetesVersion = defaults.KubernetesVersion
+	}
+	if c.CertificatesDir == "" {
+		c.CertificatesDir = defaults.CertificatesDir
+	}
+	if len(c.APIServerCertSANs) == 0 {
+		c.APIServerCertSANs = defaults.APIServerCertSANs
+	}
+	if c.CRISocket == "" {
+		c.CRISocket = defaults.CRISocket
+	}
+	if c.NodeName == "" {
+		c.NodeName = defaults.NodeName
+	}
+	if c.AdvertiseAddress == "" {
+		c.AdvertiseAddress = defaults.AdvertiseAddress
+	}
+	if c.BindPort == 0 {
+		c.BindPort = defaults.BindPort
+	}
+	if c.ControlPlaneEndpoint == "" {
+		c.ControlPlaneEndpoint = defaults.ControlPlaneEndpoint
+	}
+	if c.DNSDomain == "" {
+		c.DNSDomain = defaults.DNSDomain
+	}
+	if c.ServiceSubnet == "" {
+		c.ServiceSubnet = defaults.ServiceSubnet
+	}
+	if len(c.EtcdServerCertSANs) == 0 {
+		c.EtcdServerCertSANs = defaults.EtcdServerCertSANs
+	}
+	if len(c.EtcdPeerCertSANs) == 0 {
+		c.EtcdPeerCertSANs = defaults.EtcdPeerCertSANs
+	}
+	if c.ExternalEtcd == nil {
+		c.ExternalEtcd = defaults.ExternalEtcd
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pytimer/certadm/pkg/kubeadm"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog"
+)
+
+// configMapConfigKeys is the keys of the kubeadm-config ConfigMap which store the cluster configuration,
+// kubeadm v1.11 stores the MasterConfiguration and the later versions store the ClusterConfiguration.
+var configMapConfigKeys = []string{"MasterConfiguration", "ClusterConfiguration"}
+
+// configMap is the kube-system/kubeadm-config ConfigMap dumped by `kubectl get configmap -o yaml`.
+type configMap struct {
+	Kind string            `yaml:"kind"`
+	Data map[string]string `yaml:"data"`
+}
+
+// clusterStatus is the ClusterStatus stored in the kubeadm-config ConfigMap by kubeadm v1.12 - v1.21.
+type clusterStatus struct {
+	APIEndpoints map[string]apiEndpoint `yaml:"apiEndpoints"`
+}
+
+type apiEndpoint struct {
+	AdvertiseAddress string `yaml:"advertiseAddress"`
+	BindPort         int32  `yaml:"bindPort"`
+}
+
+// FromConfigMapFile loads the kubeadm Config from the dumped kubeadm-config ConfigMap file. The advertise address
+// and the bind port of the node are loaded from the ClusterStatus if the ConfigMap has it.
+func FromConfigMapFile(f string) (*kubeadm.Config, error) {
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	cm := &configMap{}
+	if err := yaml.Unmarshal(b, cm); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the ConfigMap %s", f)
+	}
+	if cm.Kind != "ConfigMap" {
+		return nil, errors.Errorf("%s is not a ConfigMap, kind is %q", f, cm.Kind)
+	}
+
+	docs := []kubeadm.Document{}
+	for _, key := range configMapConfigKeys {
+		data, ok := cm.Data[key]
+		if !ok {
+			continue
+		}
+		d, err := kubeadm.SplitYAMLDocuments([]byte(data))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode the %s of the ConfigMap %s", key, f)
+		}
+		docs = append(docs, d...)
+	}
+	if len(docs) == 0 {
+		return nil, errors.Errorf("the ConfigMap %s has no %s", f, strings.Join(configMapConfigKeys, " or "))
+	}
+
+	c, err := kubeadm.FetchConfigurationFromDocuments(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, ok := cm.Data["ClusterStatus"]; ok {
+		status := &clusterStatus{}
+		if err := yaml.Unmarshal([]byte(data), status); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode the ClusterStatus of the ConfigMap %s", f)
+		}
+		setNodeAPIEndpoint(c, status)
+	}
+	return c, nil
+}
+
+// setNodeAPIEndpoint sets the API endpoint of the node which name is the hostname,
+// or the only API endpoint of the cluster.
+func setNodeAPIEndpoint(c *kubeadm.Config, status *clusterStatus) {
+	nodeName, err := os.Hostname()
+	if err != nil {
+		klog.Warningf("[config] failed to get the hostname: %v", err)
+	}
+	nodeName = strings.ToLower(nodeName)
+
+	if endpoint, ok := status.APIEndpoints[nodeName]; ok {
+		c.NodeName = nodeName
+		c.AdvertiseAddress = endpoint.AdvertiseAddress
+		c.BindPort = endpoint.BindPort
+		return
+	}
+	if len(status.APIEndpoints) == 1 {
+		for name, endpoint := range status.APIEndpoints {
+			c.NodeName = name
+			c.AdvertiseAddress = endpoint.AdvertiseAddress
+			c.BindPort = endpoint.BindPort
+		}
+		return
+	}
+	klog.Warningf("[config] the ClusterStatus has no API endpoint of the node %s", nodeName)
+}
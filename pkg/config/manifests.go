@@ -0,0 +1,128 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pytimer/certadm/pkg/kubeadm"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog"
+)
+
+// staticPod is the part of the static pod manifest used to fill the kubeadm Config.
+type staticPod struct {
+	Kind string `yaml:"kind"`
+	Spec struct {
+		Containers []container `yaml:"containers"`
+	} `yaml:"spec"`
+}
+
+type container struct {
+	Name    string   `yaml:"name"`
+	Image   string   `yaml:"image"`
+	Command []string `yaml:"command"`
+}
+
+// flags returns the "--name=value" arguments of the container command.
+func (c *container) flags() map[string]string {
+	flags := map[string]string{}
+	for _, arg := range c.Command {
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)
+		if len(kv) == 2 {
+			flags[kv[0]] = kv[1]
+		} else {
+			flags[kv[0]] = ""
+		}
+	}
+	return flags
+}
+
+// FromManifests fills the kubeadm Config from the kube-apiserver and etcd static pod manifests in the manifestsDir,
+// the flags of the kube-apiserver override the fields derived from the certificates.
+func FromManifests(c *kubeadm.Config, manifestsDir string) error {
+	containers, err := loadContainers(manifestsDir)
+	if err != nil {
+		return err
+	}
+
+	apiserver, ok := containers["kube-apiserver"]
+	if !ok {
+		klog.Warningf("[config] kube-apiserver static pod manifest not found in %s, skip it", manifestsDir)
+		return nil
+	}
+	flags := apiserver.flags()
+
+	if v := imageTag(apiserver.Image); v != "" {
+		c.KubernetesVersion = v
+	}
+	if v, ok := flags["advertise-address"]; ok && v != "" {
+		c.AdvertiseAddress = v
+	}
+	if v, ok := flags["secure-port"]; ok && v != "" {
+		port, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return errors.Wrapf(err, "invalid kube-apiserver flag --secure-port=%s", v)
+		}
+		c.BindPort = int32(port)
+	}
+	if v, ok := flags["service-cluster-ip-range"]; ok && v != "" {
+		c.ServiceSubnet = v
+	}
+
+	// etcd runs on the control-plane node if there is the etcd static pod, the kube-apiserver
+	// connects to the external etcd cluster otherwise.
+	if _, ok := containers["etcd"]; !ok {
+		if v, ok := flags["etcd-servers"]; ok && v != "" {
+			c.ExternalEtcd = &kubeadm.ExternalEtcd{
+				Endpoints: strings.Split(v, ","),
+				CAFile:    flags["etcd-cafile"],
+				CertFile:  flags["etcd-certfile"],
+				KeyFile:   flags["etcd-keyfile"],
+			}
+		}
+	}
+	return nil
+}
+
+// loadContainers returns the first container of the static pods in the manifestsDir by the container name.
+func loadContainers(manifestsDir string) (map[string]*container, error) {
+	files, err := filepath.Glob(filepath.Join(manifestsDir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	containers := map[string]*container{}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		pod := &staticPod{}
+		if err := yaml.Unmarshal(b, pod); err != nil {
+			klog.Warningf("[config] failed to decode the static pod manifest %s, skip it: %v", f, err)
+			continue
+		}
+		if pod.Kind != "Pod" || len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		containers[pod.Spec.Containers[0].Name] = &pod.Spec.Containers[0]
+	}
+	return containers, nil
+}
+
+// imageTag returns the tag of the image, e.g. v1.18.0 of k8s.gcr.io/kube-apiserver:v1.18.0.
+func imageTag(image string) string {
+	image = strings.SplitN(image, "@", 2)[0]
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i+1:], "/") {
+		return ""
+	}
+	return image[i+1:]
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"crypto/x509"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/kubeadm"
+	"github.com/pytimer/certadm/pkg/kubeconfig"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+// FromCluster reconstructs the kubeadm configuration from the certificates, kubeconfig files and static pod manifests
+// in the kubernetesDir. The fields which can't be derived are left empty, so kubeadm uses its defaults.
+// The CRISocket is not stored in the Kubernetes directory, the caller should set it.
+func FromCluster(kubernetesDir string) (*kubeadm.Config, error) {
+	certificatesDir := filepath.Join(kubernetesDir, "pki")
+	apiserverCert, err := pkiutil.TryLoadCertFromDisk(certificatesDir, "apiserver")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the apiserver certificate")
+	}
+	etcdServerCert, err := tryLoadCertFromDisk(certificatesDir, "etcd/server")
+	if err != nil {
+		return nil, err
+	}
+	etcdPeerCert, err := tryLoadCertFromDisk(certificatesDir, "etcd/peer")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &kubeadm.Config{
+		CertificatesDir:  certificatesDir,
+		DNSDomain:        dnsDomain(apiserverCert),
+		AdvertiseAddress: advertiseAddress(apiserverCert, etcdServerCert),
+	}
+	c.NodeName, err = nodeName(kubernetesDir, apiserverCert, etcdServerCert)
+	if err != nil {
+		return nil, err
+	}
+	if err := FromManifests(c, filepath.Join(kubernetesDir, "manifests")); err != nil {
+		return nil, err
+	}
+	if err := setAPIEndpoint(c, kubernetesDir); err != nil {
+		return nil, err
+	}
+
+	// the extra SANs are not set yet, so the expected SANs are the defaults of kubeadm.
+	c.APIServerCertSANs = extraSANs(apiserverCert, sets.NewString(ExpectedSANs(c, "apiserver")...))
+	if etcdServerCert != nil {
+		c.EtcdServerCertSANs = extraSANs(etcdServerCert, sets.NewString(ExpectedSANs(c, "etcd-server")...))
+	}
+	if etcdPeerCert != nil {
+		c.EtcdPeerCertSANs = extraSANs(etcdPeerCert, sets.NewString(ExpectedSANs(c, "etcd-peer")...))
+	}
+
+	return c, nil
+}
+
+// tryLoadCertFromDisk returns nil if the certificate not exists, e.g. the etcd certificates of the external etcd.
+func tryLoadCertFromDisk(certificatesDir, name string) (*x509.Certificate, error) {
+	if exists, err := path.Exists(path.CheckFollowSymlink, filepath.Join(certificatesDir, name+".crt")); err != nil {
+		return nil, err
+	} else if !exists {
+		klog.Warningf("[config] certificate %s not exists, skip it", name)
+		return nil, nil
+	}
+
+	cert, err := pkiutil.TryLoadCertFromDisk(certificatesDir, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the %s certificate", name)
+	}
+	return cert, nil
+}
+
+// dnsDomain returns the dns domain of the kubernetes.default.svc.<domain> SAN of the apiserver certificate.
+func dnsDomain(apiserverCert *x509.Certificate) string {
+	for _, name := range apiserverCert.DNSNames {
+		if strings.HasPrefix(name, "kubernetes.default.svc.") {
+			return strings.TrimPrefix(name, "kubernetes.default.svc.")
+		}
+	}
+	klog.Warningf("[config] the apiserver certificate has no kubernetes.default.svc.<domain> SAN, using the default dns domain %s", defaultDNSDomain)
+	return defaultDNSDomain
+}
+
+// advertiseAddress returns the IP address advertised by the API server. kubeadm signs the apiserver certificate
+// with the API server virtual IP and the advertise address in order, the etcd server certificate is used
+// if the apiserver certificate has no advertise address.
+func advertiseAddress(apiserverCert, etcdServerCert *x509.Certificate) string {
+	if len(apiserverCert.IPAddresses) >= 2 {
+		return apiserverCert.IPAddresses[1].String()
+	}
+	if etcdServerCert != nil {
+		for _, ip := range etcdServerCert.IPAddresses {
+			if !ip.IsLoopback() {
+				return ip.String()
+			}
+		}
+	}
+	klog.Warning("[config] failed to detect the advertise address")
+	return ""
+}
+
+// nodeName returns the node name from the kubelet client certificate of kubelet.conf, the common name
+// of the etcd server certificate or the first DNS SAN of the apiserver certificate in order.
+func nodeName(kubernetesDir string, apiserverCert, etcdServerCert *x509.Certificate) (string, error) {
+	kubeletConf := filepath.Join(kubernetesDir, "kubelet.conf")
+	if exists, err := path.Exists(path.CheckFollowSymlink, kubeletConf); err != nil {
+		return "", err
+	} else if exists {
+		cert, err := kubeconfig.LoadClientCertificate(kubeletConf)
+		if err != nil {
+			klog.Warningf("[config] failed to load the kubelet client certificate: %v", err)
+		}
+		if cert != nil && strings.HasPrefix(cert.Subject.CommonName, certs.NodeUserPrefix) {
+			return strings.TrimPrefix(cert.Subject.CommonName, certs.NodeUserPrefix), nil
+		}
+	}
+
+	if etcdServerCert != nil && etcdServerCert.Subject.CommonName != "" {
+		return etcdServerCert.Subject.CommonName, nil
+	}
+
+	if len(apiserverCert.DNSNames) > 0 && !strings.HasPrefix(apiserverCert.DNSNames[0], "kubernetes") {
+		return apiserverCert.DNSNames[0], nil
+	}
+	klog.Warning("[config] failed to detect the node name")
+	return "", nil
+}
+
+// setAPIEndpoint sets the bind port and the control plane endpoint from the server of the kubeconfig files.
+// The server which host is the advertise address is the local API endpoint, the server of admin.conf
+// is the control plane endpoint otherwise.
+func setAPIEndpoint(c *kubeadm.Config, kubernetesDir string) error {
+	for _, kf := range []string{"admin.conf", "controller-manager.conf", "scheduler.conf"} {
+		kubeconfigPath := filepath.Join(kubernetesDir, kf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, kubeconfigPath); err != nil {
+			return err
+		} else if !exists {
+			continue
+		}
+
+		kc, err := kubeconfig.LoadFromFile(kubeconfigPath)
+		if err != nil {
+			return err
+		}
+		cluster, err := kubeconfig.GetCurrentCluster(kc)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get cluster from %s", kubeconfigPath)
+		}
+		u, err := url.Parse(cluster.Server)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse the server of %s", kubeconfigPath)
+		}
+
+		if u.Hostname() == c.AdvertiseAddress {
+			if c.BindPort == 0 && u.Port() != "" {
+				port, err := strconv.ParseInt(u.Port(), 10, 32)
+				if err != nil {
+					return errors.Wrapf(err, "invalid port of the server %s", cluster.Server)
+				}
+				c.BindPort = int32(port)
+			}
+		} else if kf == "admin.conf" {
+			c.ControlPlaneEndpoint = u.Host
+		}
+	}
+	return nil
+}
+
+// extraSANs returns the DNS names and IP addresses of the certificate which are not the defaults.
+func extraSANs(cert *x509.Certificate, defaults sets.String) []string {
+	sans := []string{}
+	seen := sets.NewString()
+	for _, name := range cert.DNSNames {
+		if defaults.Has(name) || seen.Has(name) {
+			continue
+		}
+		seen.Insert(name)
+		sans = append(sans, name)
+	}
+	for _, ip := range cert.IPAddresses {
+		if defaults.Has(ip.String()) || seen.Has(ip.String()) {
+			continue
+		}
+		seen.Insert(ip.String())
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/kubeadm"
+
+	"k8s.io/klog"
+)
+
+const (
+	// defaultDNSDomain is the dns domain used by kubeadm if the networking.dnsDomain is not set
+	defaultDNSDomain = "cluster.local"
+	// defaultAPIServerVirtualIP is the first IP of the default kubeadm service subnet 10.96.0.0/12
+	defaultAPIServerVirtualIP = "10.96.0.1"
+	// defaultRouteProbeAddress is an address of TEST-NET-3 routed by the default route
+	defaultRouteProbeAddress = "203.0.113.1:80"
+)
+
+// ExpectedSANs returns the SANs which kubeadm signs the certificate with according to the Config,
+// the name is one of apiserver, etcd-server and etcd-peer.
+func ExpectedSANs(c *kubeadm.Config, name string) []string {
+	sans := nodeSANs(c)
+	switch name {
+	case "apiserver":
+		dnsDomain := c.DNSDomain
+		if dnsDomain == "" {
+			dnsDomain = defaultDNSDomain
+		}
+		sans = append(sans,
+			"kubernetes",
+			"kubernetes.default",
+			"kubernetes.default.svc",
+			"kubernetes.default.svc."+dnsDomain,
+			apiServerVirtualIP(c.ServiceSubnet),
+		)
+		if c.ControlPlaneEndpoint != "" {
+			host, _, err := net.SplitHostPort(c.ControlPlaneEndpoint)
+			if err != nil {
+				host = c.ControlPlaneEndpoint
+			}
+			sans = append(sans, certs.CanonicalSAN(host))
+		}
+		sans = append(sans, c.APIServerCertSANs...)
+	case "etcd-server":
+		sans = append(sans, "localhost", "127.0.0.1", "::1")
+		sans = append(sans, c.EtcdServerCertSANs...)
+	case "etcd-peer":
+		sans = append(sans, "localhost", "127.0.0.1", "::1")
+		sans = append(sans, c.EtcdPeerCertSANs...)
+	default:
+		return nil
+	}
+
+	for i := range sans {
+		sans[i] = certs.CanonicalSAN(sans[i])
+	}
+	return sans
+}
+
+// ApplySANChanges adds the SANs to or removes them from the extra SANs of the Config.
+func ApplySANChanges(c *kubeadm.Config, changes *certs.SANChanges) {
+	c.APIServerCertSANs = changes.Apply("apiserver", c.APIServerCertSANs)
+	c.EtcdServerCertSANs = changes.Apply("etcd-server", c.EtcdServerCertSANs)
+	c.EtcdPeerCertSANs = changes.Apply("etcd-peer", c.EtcdPeerCertSANs)
+}
+
+// apiServerVirtualIP returns the first IP of the service subnet, which kubeadm adds to the apiserver certificate.
+func apiServerVirtualIP(serviceSubnet string) string {
+	if serviceSubnet == "" {
+		return defaultAPIServerVirtualIP
+	}
+	// the first subnet is used by the dual-stack cluster
+	_, ipNet, err := net.ParseCIDR(strings.Split(serviceSubnet, ",")[0])
+	if err != nil {
+		klog.Warningf("[config] invalid service subnet %s: %v", serviceSubnet, err)
+		return defaultAPIServerVirtualIP
+	}
+	ip := make(net.IP, len(ipNet.IP))
+	copy(ip, ipNet.IP)
+	ip[len(ip)-1]++
+	return ip.String()
+}
+
+// nodeSANs returns the node name and the advertise address which kubeadm always adds to the certificates,
+// they default to the hostname and the IP address of the default route as kubeadm does.
+func nodeSANs(c *kubeadm.Config) []string {
+	sans := []string{}
+	nodeName := c.NodeName
+	if nodeName == "" {
+		nodeName = defaultNodeName()
+	}
+	if nodeName != "" {
+		sans = append(sans, nodeName)
+	}
+	advertiseAddress := c.AdvertiseAddress
+	if advertiseAddress == "" {
+		advertiseAddress = defaultAdvertiseAddress()
+	}
+	if advertiseAddress != "" {
+		sans = append(sans, certs.CanonicalSAN(advertiseAddress))
+	}
+	return sans
+}
+
+// defaultNodeName returns the lower-case hostname, which kubeadm uses if the nodeRegistration.name is not set.
+func defaultNodeName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		klog.Warningf("[config] failed to get the hostname: %v", err)
+		return ""
+	}
+	return strings.ToLower(hostname)
+}
+
+// defaultAdvertiseAddress returns the IP address of the default route, which kubeadm uses if the
+// localAPIEndpoint.advertiseAddress is not set. Connecting the UDP socket sends nothing, the kernel
+// only chooses the source address by the route.
+func defaultAdvertiseAddress() string {
+	conn, err := net.Dial("udp4", defaultRouteProbeAddress)
+	if err != nil {
+		klog.Warningf("[config] failed to detect the IP address of the default route: %v", err)
+		return ""
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String()
+}
@@ -0,0 +1,29 @@
+package v1alpha1
+
+const (
+	// GroupName is the group name of the certadm configuration API
+	GroupName = "config.certadm.pytimer.github.com"
+
+	// SchemeGroupVersion is group version used to configure certadm
+	SchemeGroupVersion = GroupName + "/v1alpha1"
+
+	// CertadmConfigurationKind is the kind of the CertadmConfiguration
+	CertadmConfigurationKind = "CertadmConfiguration"
+)
+
+// CertadmConfiguration is the configuration of the certificates renewed by certadm.
+type CertadmConfiguration struct {
+	Kind       string `json:"kind" yaml:"kind"`
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+
+	// Validity is the validity period of the renewed certificates, e.g. 8760h, 365d or 1y.
+	Validity string `json:"validity,omitempty" yaml:"validity,omitempty"`
+	// Certificates overrides the configuration by the certificate name, e.g. apiserver or admin.conf.
+	Certificates map[string]CertificateConfiguration `json:"certificates,omitempty" yaml:"certificates,omitempty"`
+}
+
+// CertificateConfiguration is the configuration of a certificate.
+type CertificateConfiguration struct {
+	// Validity is the validity period of the certificate.
+	Validity string `json:"validity,omitempty" yaml:"validity,omitempty"`
+}
@@ -3,9 +3,8 @@ package constants
 import "time"
 
 const (
-	TempDirPrefix              = "certadm"
-	DefaultTempCertificatesDir = "/tmp/certadm"
-	KubeletCertificatesPath    = "/var/lib/kubelet/pki"
+	DefaultBackupDir        = "/var/lib/certadm/backups"
+	KubeletCertificatesPath = "/var/lib/kubelet/pki"
 
 	DefaultKubeadmVersion    = "v1.11.0"
 	DefaultKubeadmAPIVersion = "v1alpha2"
@@ -20,6 +19,35 @@ const (
 
 	// DefaultDockerCRISocket defines the default Docker CRI socket
 	DefaultDockerCRISocket = "/var/run/dockershim.sock"
+
+	// CertificateValidity defines the validity for all the signed certificates generated by certadm
+	CertificateValidity = time.Hour * 24 * 365
+
+	// CAValidity defines the validity for the CA certificates generated by certadm
+	CAValidity = time.Hour * 24 * 365 * 10
+
+	// CACertAndKeyBaseName defines certificate authority base name
+	CACertAndKeyBaseName = "ca"
+	// EtcdCACertAndKeyBaseName defines etcd's CA certificate and key base name
+	EtcdCACertAndKeyBaseName = "etcd/ca"
+	// FrontProxyCACertAndKeyBaseName defines front proxy CA certificate and key base name
+	FrontProxyCACertAndKeyBaseName = "front-proxy-ca"
+
+	// ServiceAccountKeyBaseName defines SA key base name
+	ServiceAccountKeyBaseName = "sa"
+	// ServiceAccountOldPublicKeyBaseName defines the base name of the SA public key replaced by the key rotation
+	ServiceAccountOldPublicKeyBaseName = "sa-old"
+
+	// SystemPrivilegedGroup defines the well-known group for the apiservers. This group is also superuser by default
+	// (i.e. bound to the cluster-admin ClusterRole)
+	SystemPrivilegedGroup = "system:masters"
+
+	// NamespaceSystem is the system namespace where the control plane components are placed
+	NamespaceSystem = "kube-system"
+	// ExtensionAPIServerAuthenticationConfigMap is the ConfigMap which the aggregated API servers read the client CAs from
+	ExtensionAPIServerAuthenticationConfigMap = "extension-apiserver-authentication"
+	// RequestHeaderClientCAFileKey is the key of the front proxy CA bundle in the ExtensionAPIServerAuthenticationConfigMap
+	RequestHeaderClientCAFileKey = "requestheader-client-ca-file"
 )
 
 var ControlPlaneNames = []string{
@@ -0,0 +1,87 @@
+package kubeadm
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// GroupName is the API group of the kubeadm configuration
+const GroupName = "kubeadm.k8s.io"
+
+var yamlSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// TypeMeta describes the apiVersion and kind of a kubeadm config document.
+type TypeMeta struct {
+	APIVersion string `yaml:"apiVersion,omitempty"`
+	Kind       string `yaml:"kind,omitempty"`
+}
+
+// Document is a yaml document of the kubeadm config file.
+type Document struct {
+	TypeMeta
+	Data []byte
+}
+
+// Group returns the API group of the document.
+func (d *Document) Group() string {
+	if i := strings.Index(d.APIVersion, "/"); i >= 0 {
+		return d.APIVersion[:i]
+	}
+	return ""
+}
+
+// Version returns the API version of the document without the group.
+func (d *Document) Version() string {
+	if i := strings.Index(d.APIVersion, "/"); i >= 0 {
+		return d.APIVersion[i+1:]
+	}
+	return d.APIVersion
+}
+
+// GroupVersion returns the apiVersion of the kubeadm config document in the version.
+func GroupVersion(version string) string {
+	return GroupName + "/" + version
+}
+
+// SplitYAMLDocuments splits the "---" separated yaml documents, the empty documents are skipped.
+func SplitYAMLDocuments(b []byte) ([]Document, error) {
+	docs := []Document{}
+	for _, data := range yamlSeparator.Split(string(b), -1) {
+		if strings.TrimSpace(data) == "" {
+			continue
+		}
+
+		d := Document{Data: []byte(data)}
+		if err := yaml.Unmarshal(d.Data, &d.TypeMeta); err != nil {
+			return nil, errors.Wrap(err, "failed to decode the apiVersion and kind")
+		}
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
+
+// ReadDocumentsFromFile reads the kubeadm config file and splits it into documents.
+func ReadDocumentsFromFile(f string) ([]Document, error) {
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
+	return SplitYAMLDocuments(b)
+}
+
+// MarshalDocuments encodes the objects as the "---" separated yaml documents.
+func MarshalDocuments(objs ...interface{}) ([]byte, error) {
+	docs := []string{}
+	for _, obj := range objs {
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, string(b))
+	}
+	return []byte(strings.Join(docs, "---\n")), nil
+}
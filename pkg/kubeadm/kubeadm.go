@@ -7,6 +7,7 @@ import (
 
 	"github.com/pytimer/certadm/pkg/constants"
 
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/klog"
 	"k8s.io/utils/exec"
@@ -39,22 +40,34 @@ func getKubeadmAPIVersion(v string) string {
 		return "v1alpha3"
 	case compareSemanticVersion(semanticVersion, "v1.13.0") >= 0 && compareSemanticVersion(semanticVersion, "v1.15.0") < 0:
 		return "v1beta1"
-	case compareSemanticVersion(semanticVersion, "v1.15.0") >= 0:
+	case compareSemanticVersion(semanticVersion, "v1.15.0") >= 0 && compareSemanticVersion(semanticVersion, "v1.22.0") < 0:
 		return "v1beta2"
+	case compareSemanticVersion(semanticVersion, "v1.22.0") >= 0 && compareSemanticVersion(semanticVersion, "v1.31.0") < 0:
+		return "v1beta3"
+	case compareSemanticVersion(semanticVersion, "v1.31.0") >= 0:
+		return "v1beta4"
 	}
 
 	return constants.DefaultKubeadmAPIVersion
 }
 
 func getKubeadmVersion() string {
+	kubeadmVersion, err := GetKubeadmVersion()
+	if err != nil {
+		return constants.DefaultKubeadmVersion
+	}
+	return kubeadmVersion
+}
+
+// GetKubeadmVersion returns the kubeadm version via `kubeadm version -o short`
+func GetKubeadmVersion() (string, error) {
 	klog.V(3).Info("get kubeadm version")
-	kubeadmVersion := constants.DefaultKubeadmVersion
 	c := exec.New().Command(kubeadmExecPath, "version", "-o", "short")
 	b, err := c.Output()
-	if err == nil {
-		kubeadmVersion = strings.TrimRight(string(b), "\n")
+	if err != nil {
+		return "", err
 	}
-	return kubeadmVersion
+	return strings.TrimRight(string(b), "\n"), nil
 }
 
 // GetKubeadmAPIVersion returns the kubeadm version via `kubeadm version -o short`
@@ -65,30 +78,129 @@ func GetKubeadmAPIVersion() string {
 }
 
 func PhasesCreateCerts(configFile string) ([]byte, error) {
-	kubeadmAPIVersion := GetKubeadmAPIVersion()
-	args := GetKubeadmFactory(kubeadmAPIVersion).RenewCertsCommandArgs()
-	args = append(args, fmt.Sprintf("--config=%s", configFile))
-	klog.V(2).Infof("[kubeadm-certs] renew certificates command args: '%s %s'", kubeadmExecPath, strings.Join(args, " "))
-	cmd := exec.New().Command(kubeadmExecPath, args...)
+	args, err := PhasesCreateCertsCommand(configFile)
+	if err != nil {
+		return nil, err
+	}
+	klog.V(2).Infof("[kubeadm-certs] renew certificates command args: '%s'", strings.Join(args, " "))
+	cmd := exec.New().Command(args[0], args[1:]...)
 	return cmd.CombinedOutput()
 }
 
-func PhasesCreateKubeConfig(configFile string) ([]byte, error) {
-	kubeadmAPIVersion := GetKubeadmAPIVersion()
-	args := GetKubeadmFactory(kubeadmAPIVersion).RenewKubeConfigCommandArgs()
+// PhasesCreateCertsCommand returns the kubeadm command and args used to create the certificates.
+func PhasesCreateCertsCommand(configFile string) ([]string, error) {
+	factory, err := getFactory()
+	if err != nil {
+		return nil, err
+	}
+	args := factory.RenewCertsCommandArgs()
 	args = append(args, fmt.Sprintf("--config=%s", configFile))
-	klog.V(2).Infof("[kubeadm-kubeconfig] renew kubeconfig command args: '%s %s'", kubeadmExecPath, strings.Join(args, " "))
-	cmd := exec.New().Command(kubeadmExecPath, args...)
+	return append([]string{kubeadmExecPath}, args...), nil
+}
+
+// RenewCert renews a single certificate or kubeconfig file by the name via kubeadm.
+func RenewCert(configFile, name string) ([]byte, error) {
+	args, err := RenewCertCommand(configFile, name)
+	if err != nil {
+		return nil, err
+	}
+	if args == nil {
+		return nil, errors.Errorf("the kubeadm version can't renew the %s certificate", name)
+	}
+	klog.V(2).Infof("[kubeadm-certs] renew %s command args: '%s'", name, strings.Join(args, " "))
+	cmd := exec.New().Command(args[0], args[1:]...)
 	return cmd.CombinedOutput()
 }
 
+// RenewCertCommand returns the kubeadm command and args used to renew a single certificate or kubeconfig file,
+// it returns nil if the installed kubeadm can't renew a single certificate.
+func RenewCertCommand(configFile, name string) ([]string, error) {
+	factory, err := getFactory()
+	if err != nil {
+		return nil, err
+	}
+	args := factory.RenewCertCommandArgs(version.MustParseGeneric(getKubeadmVersion()), name)
+	if args == nil {
+		return nil, nil
+	}
+	args = append(args, fmt.Sprintf("--config=%s", configFile))
+	return append([]string{kubeadmExecPath}, args...), nil
+}
+
+// CertsRenewCommandArgs returns the args of `kubeadm certs renew` of the kubeadm version, it is
+// `kubeadm alpha certs renew` before v1.20.
+func CertsRenewCommandArgs(kubeadmVersion *version.Version) []string {
+	if kubeadmVersion.LessThan(version.MustParseGeneric("v1.20.0")) {
+		return []string{"alpha", "certs", "renew"}
+	}
+	return []string{"certs", "renew"}
+}
+
 // FetchConfigurationFromConfigFile returns the configurations from the kubeadm config file
 func FetchConfigurationFromConfigFile(configFile string) (*Config, error) {
+	docs, err := ReadDocumentsFromFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return FetchConfigurationFromDocuments(docs)
+}
+
+// FetchConfigurationFromDocuments returns the configurations from the kubeadm config documents
+func FetchConfigurationFromDocuments(docs []Document) (*Config, error) {
+	factory, err := getConfigFactory(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	return factory.LoadConfig(docs)
+}
+
+// MarshalConfig encodes the Config as the kubeadm config file of the apiVersion, e.g. v1beta2,
+// the API version of the installed kubeadm is used if the apiVersion is empty.
+func MarshalConfig(c *Config, apiVersion string) ([]byte, error) {
+	if apiVersion == "" {
+		apiVersion = GetKubeadmAPIVersion()
+	}
+	factory := GetKubeadmFactory(apiVersion)
+	if factory == nil {
+		return nil, errors.Errorf("kubeadm API version %s is not supported", apiVersion)
+	}
+	return factory.MarshalConfig(c)
+}
+
+// getConfigFactory returns the Factory of the API version used by the kubeadm documents,
+// the installed kubeadm version is used if the config file has no kubeadm document.
+func getConfigFactory(docs []Document) (Factory, error) {
+	version := ""
+	for _, d := range docs {
+		if d.Group() != GroupName {
+			continue
+		}
+		if version != "" && version != d.Version() {
+			return nil, errors.Errorf("the kubeadm config documents use different API versions: %s and %s", version, d.Version())
+		}
+		version = d.Version()
+	}
+	if version == "" {
+		klog.Warningf("[kubeadm] no %s document found in the config file, using the API version of the installed kubeadm", GroupName)
+		return getFactory()
+	}
+
+	factory := GetKubeadmFactory(version)
+	if factory == nil {
+		return nil, errors.Errorf("kubeadm API version %s is not supported", version)
+	}
+	return factory, nil
+}
+
+// getFactory returns the Factory of the installed kubeadm version.
+func getFactory() (Factory, error) {
 	v := GetKubeadmAPIVersion()
 	factory := GetKubeadmFactory(v)
-
-	c, err := factory.LoadConfigFromFile(configFile)
-	return c, err
+	if factory == nil {
+		return nil, errors.Errorf("kubeadm API version %s is not supported", v)
+	}
+	return factory, nil
 }
 
 // GetCertificatesDirFromConfigFile returns the certificates directory from the kubeadm config file
@@ -1,11 +1,17 @@
 package kubeadm
 
+import "k8s.io/apimachinery/pkg/util/version"
+
 var versions = make(map[string]Factory)
 
 type Factory interface {
 	RenewCertsCommandArgs() []string
-	RenewKubeConfigCommandArgs() []string
-	LoadConfigFromFile(f string) (*Config, error)
+	// RenewCertCommandArgs returns the args used to renew a single certificate or kubeconfig file by the name,
+	// e.g. apiserver or admin.conf. It returns nil if the kubeadm version can't renew a single certificate.
+	RenewCertCommandArgs(kubeadmVersion *version.Version, name string) []string
+	LoadConfig(docs []Document) (*Config, error)
+	// MarshalConfig encodes the Config as the kubeadm config documents of the API version.
+	MarshalConfig(c *Config) ([]byte, error)
 }
 
 func Add(key string, k Factory) {
@@ -1,7 +1,35 @@
 package kubeadm
 
 type Config struct {
+	// KubernetesVersion is the target version of the control plane
+	KubernetesVersion string   `yaml:"kubernetesVersion,omitempty"`
 	CertificatesDir   string   `yaml:"certificatesDir"`
 	APIServerCertSANs []string `yaml:"apiServerCertSANs,omitempty"`
 	CRISocket         string   `yaml:"criSocket,omitempty"`
+	// NodeName is the name of the control-plane node
+	NodeName string `yaml:"nodeName,omitempty"`
+	// AdvertiseAddress is the IP address the API server advertises on the control-plane node
+	AdvertiseAddress string `yaml:"advertiseAddress,omitempty"`
+	// BindPort is the secure port the API server binds to
+	BindPort int32 `yaml:"bindPort,omitempty"`
+	// ControlPlaneEndpoint is the stable IP address or DNS name of the control plane
+	ControlPlaneEndpoint string `yaml:"controlPlaneEndpoint,omitempty"`
+	// DNSDomain is the dns domain used by the Kubernetes services
+	DNSDomain string `yaml:"dnsDomain,omitempty"`
+	// ServiceSubnet is the subnet used by the Kubernetes services
+	ServiceSubnet string `yaml:"serviceSubnet,omitempty"`
+	// EtcdServerCertSANs sets extra Subject Alternative Names for the etcd server signing cert.
+	EtcdServerCertSANs []string `yaml:"etcdServerCertSANs,omitempty"`
+	// EtcdPeerCertSANs sets extra Subject Alternative Names for the etcd peer signing cert.
+	EtcdPeerCertSANs []string `yaml:"etcdPeerCertSANs,omitempty"`
+	// ExternalEtcd is the external etcd cluster, it is nil if etcd runs on the control-plane node
+	ExternalEtcd *ExternalEtcd `yaml:"externalEtcd,omitempty"`
+}
+
+// ExternalEtcd describes the external etcd cluster used by the API server.
+type ExternalEtcd struct {
+	Endpoints []string `yaml:"endpoints"`
+	CAFile    string   `yaml:"caFile"`
+	CertFile  string   `yaml:"certFile"`
+	KeyFile   string   `yaml:"keyFile"`
 }
@@ -1,10 +1,68 @@
 package v1alpha2
 
-type Configuration struct {
+import "github.com/pytimer/certadm/pkg/kubeadm"
+
+// MasterConfiguration contains a list of elements which make up master's configuration object.
+type MasterConfiguration struct {
+	kubeadm.TypeMeta `yaml:",inline"`
+
+	// API holds configuration for the k8s apiserver.
+	API        API        `yaml:"api,omitempty"`
+	Etcd       Etcd       `yaml:"etcd,omitempty"`
+	Networking Networking `yaml:"networking,omitempty"`
+	// KubernetesVersion is the target version of the control plane.
+	KubernetesVersion string `yaml:"kubernetesVersion,omitempty"`
+
 	CertificatesDir   string   `yaml:"certificatesDir"`
 	APIServerCertSANs []string `yaml:"apiServerCertSANs,omitempty"`
 	// NodeRegistration holds fields that relate to registering the new master node to the cluster
-	NodeRegistration NodeRegistrationOptions `json:"nodeRegistration,omitempty"`
+	NodeRegistration NodeRegistrationOptions `yaml:"nodeRegistration,omitempty"`
+}
+
+// API struct contains elements of API server address.
+type API struct {
+	// AdvertiseAddress sets the IP address for the API server to advertise.
+	AdvertiseAddress string `yaml:"advertiseAddress,omitempty"`
+	// ControlPlaneEndpoint for the API server.
+	ControlPlaneEndpoint string `yaml:"controlPlaneEndpoint,omitempty"`
+	// BindPort sets the secure port for the API Server to bind to.
+	BindPort int32 `yaml:"bindPort,omitempty"`
+}
+
+// Etcd contains elements describing Etcd configuration.
+type Etcd struct {
+	// Local provides configuration knobs for configuring the local etcd instance
+	Local *LocalEtcd `yaml:"local,omitempty"`
+	// External describes how to connect to an external etcd cluster
+	External *ExternalEtcd `yaml:"external,omitempty"`
+}
+
+// LocalEtcd describes that kubeadm should run an etcd cluster locally
+type LocalEtcd struct {
+	// ServerCertSANs sets extra Subject Alternative Names for the etcd server signing cert.
+	ServerCertSANs []string `yaml:"serverCertSANs,omitempty"`
+	// PeerCertSANs sets extra Subject Alternative Names for the etcd peer signing cert.
+	PeerCertSANs []string `yaml:"peerCertSANs,omitempty"`
+}
+
+// ExternalEtcd describes an external etcd cluster.
+type ExternalEtcd struct {
+	// Endpoints of etcd members.
+	Endpoints []string `yaml:"endpoints"`
+	// CAFile is an SSL Certificate Authority file used to secure etcd communication.
+	CAFile string `yaml:"caFile"`
+	// CertFile is an SSL certification file used to secure etcd communication.
+	CertFile string `yaml:"certFile"`
+	// KeyFile is an SSL key file used to secure etcd communication.
+	KeyFile string `yaml:"keyFile"`
+}
+
+// Networking contains elements describing cluster's networking configuration
+type Networking struct {
+	// DNSDomain is the dns domain used by k8s services. Defaults to "cluster.local".
+	DNSDomain string `yaml:"dnsDomain,omitempty"`
+	// ServiceSubnet is the subnet used by k8s services. Defaults to "10.96.0.0/12".
+	ServiceSubnet string `yaml:"serviceSubnet,omitempty"`
 }
 
 type NodeRegistrationOptions struct {
@@ -12,4 +70,4 @@ type NodeRegistrationOptions struct {
 
 	// CRISocket is used to retrieve container runtime info. This information will be annotated to the Node API object, for later re-use
 	CRISocket string `yaml:"criSocket,omitempty"`
-}
\ No newline at end of file
+}
@@ -1,9 +1,8 @@
 package v1alpha2
 
 import (
-	"io/ioutil"
-
 	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/version"
 
 	"github.com/pytimer/certadm/pkg/kubeadm"
 )
@@ -29,29 +28,85 @@ func (k *KubeadmAlpha2) RenewCertsCommandArgs() []string {
 	return args
 }
 
-func (k *KubeadmAlpha2) RenewKubeConfigCommandArgs() []string {
-	args := []string{
-		"alpha",
-		"phase",
-		"kubeconfig",
-		"all",
-	}
-	return args
+// RenewCertCommandArgs returns nil, this kubeadm version can't renew a single certificate.
+func (k *KubeadmAlpha2) RenewCertCommandArgs(kubeadmVersion *version.Version, name string) []string {
+	return nil
 }
 
-func (k *KubeadmAlpha2) LoadConfigFromFile(f string) (*kubeadm.Config, error) {
-	b, err := ioutil.ReadFile(f)
-	if err != nil {
-		return nil, err
+// LoadConfig decodes the kubeadm documents of the API version and merges them into the Config,
+// the documents of the other API versions and kinds are ignored.
+func (k *KubeadmAlpha2) LoadConfig(docs []kubeadm.Document) (*kubeadm.Config, error) {
+	c := &kubeadm.Config{}
+	for _, d := range docs {
+		if d.APIVersion != kubeadm.GroupVersion("v1alpha2") {
+			continue
+		}
+
+		switch d.Kind {
+		case "MasterConfiguration":
+			mc := &MasterConfiguration{}
+			if err := yaml.Unmarshal(d.Data, mc); err != nil {
+				return nil, err
+			}
+			c.KubernetesVersion = mc.KubernetesVersion
+			c.CertificatesDir = mc.CertificatesDir
+			c.APIServerCertSANs = mc.APIServerCertSANs
+			c.AdvertiseAddress = mc.API.AdvertiseAddress
+			c.BindPort = mc.API.BindPort
+			c.ControlPlaneEndpoint = mc.API.ControlPlaneEndpoint
+			c.DNSDomain = mc.Networking.DNSDomain
+			c.ServiceSubnet = mc.Networking.ServiceSubnet
+			if mc.Etcd.Local != nil {
+				c.EtcdServerCertSANs = mc.Etcd.Local.ServerCertSANs
+				c.EtcdPeerCertSANs = mc.Etcd.Local.PeerCertSANs
+			}
+			if mc.Etcd.External != nil {
+				c.ExternalEtcd = &kubeadm.ExternalEtcd{
+					Endpoints: mc.Etcd.External.Endpoints,
+					CAFile:    mc.Etcd.External.CAFile,
+					CertFile:  mc.Etcd.External.CertFile,
+					KeyFile:   mc.Etcd.External.KeyFile,
+				}
+			}
+			c.CRISocket = mc.NodeRegistration.CRISocket
+			c.NodeName = mc.NodeRegistration.Name
+		}
 	}
 
-	c := &Configuration{}
-	if err := yaml.Unmarshal(b, c); err != nil {
-		return nil, err
+	return c, nil
+}
+
+// MarshalConfig encodes the Config as the MasterConfiguration document.
+func (k *KubeadmAlpha2) MarshalConfig(c *kubeadm.Config) ([]byte, error) {
+	mc := &MasterConfiguration{
+		TypeMeta: kubeadm.TypeMeta{APIVersion: kubeadm.GroupVersion("v1alpha2"), Kind: "MasterConfiguration"},
+		API: API{
+			AdvertiseAddress:     c.AdvertiseAddress,
+			ControlPlaneEndpoint: c.ControlPlaneEndpoint,
+			BindPort:             c.BindPort,
+		},
+		Networking:        Networking{DNSDomain: c.DNSDomain, ServiceSubnet: c.ServiceSubnet},
+		KubernetesVersion: c.KubernetesVersion,
+		CertificatesDir:   c.CertificatesDir,
+		APIServerCertSANs: c.APIServerCertSANs,
+		NodeRegistration: NodeRegistrationOptions{
+			Name:      c.NodeName,
+			CRISocket: c.CRISocket,
+		},
+	}
+	if c.ExternalEtcd != nil {
+		mc.Etcd.External = &ExternalEtcd{
+			Endpoints: c.ExternalEtcd.Endpoints,
+			CAFile:    c.ExternalEtcd.CAFile,
+			CertFile:  c.ExternalEtcd.CertFile,
+			KeyFile:   c.ExternalEtcd.KeyFile,
+		}
+	} else if len(c.EtcdServerCertSANs) > 0 || len(c.EtcdPeerCertSANs) > 0 {
+		mc.Etcd.Local = &LocalEtcd{
+			ServerCertSANs: c.EtcdServerCertSANs,
+			PeerCertSANs:   c.EtcdPeerCertSANs,
+		}
 	}
 
-	return &kubeadm.Config{
-		CertificatesDir: c.CertificatesDir,
-		CRISocket: c.NodeRegistration.CRISocket,
-	}, nil
-}
\ No newline at end of file
+	return kubeadm.MarshalDocuments(mc)
+}
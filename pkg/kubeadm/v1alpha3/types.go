@@ -0,0 +1,78 @@
+package v1alpha3
+
+import "github.com/pytimer/certadm/pkg/kubeadm"
+
+// InitConfiguration contains a list of elements that is specific "kubeadm init"-only runtime information.
+type InitConfiguration struct {
+	kubeadm.TypeMeta `yaml:",inline"`
+
+	// APIEndpoint represents the endpoint of the instance of the API server to be deployed on this node.
+	APIEndpoint APIEndpoint `yaml:"apiEndpoint,omitempty"`
+	// NodeRegistration holds fields that relate to registering the new master node to the cluster
+	NodeRegistration NodeRegistrationOptions `yaml:"nodeRegistration,omitempty"`
+}
+
+// ClusterConfiguration contains cluster-wide configuration for a kubeadm cluster
+type ClusterConfiguration struct {
+	kubeadm.TypeMeta `yaml:",inline"`
+
+	Etcd       Etcd       `yaml:"etcd,omitempty"`
+	Networking Networking `yaml:"networking,omitempty"`
+	// KubernetesVersion is the target version of the control plane.
+	KubernetesVersion string `yaml:"kubernetesVersion,omitempty"`
+	// ControlPlaneEndpoint sets a stable IP address or DNS name for the control plane
+	ControlPlaneEndpoint string   `yaml:"controlPlaneEndpoint,omitempty"`
+	CertificatesDir      string   `yaml:"certificatesDir"`
+	APIServerCertSANs    []string `yaml:"apiServerCertSANs,omitempty"`
+}
+
+// APIEndpoint struct contains elements for an API server instance deployed on a node.
+type APIEndpoint struct {
+	// AdvertiseAddress sets the IP address for the API server to advertise.
+	AdvertiseAddress string `yaml:"advertiseAddress,omitempty"`
+	// BindPort sets the secure port for the API Server to bind to.
+	BindPort int32 `yaml:"bindPort,omitempty"`
+}
+
+// Etcd contains elements describing Etcd configuration.
+type Etcd struct {
+	// Local provides configuration knobs for configuring the local etcd instance
+	Local *LocalEtcd `yaml:"local,omitempty"`
+	// External describes how to connect to an external etcd cluster
+	External *ExternalEtcd `yaml:"external,omitempty"`
+}
+
+// LocalEtcd describes that kubeadm should run an etcd cluster locally
+type LocalEtcd struct {
+	// ServerCertSANs sets extra Subject Alternative Names for the etcd server signing cert.
+	ServerCertSANs []string `yaml:"serverCertSANs,omitempty"`
+	// PeerCertSANs sets extra Subject Alternative Names for the etcd peer signing cert.
+	PeerCertSANs []string `yaml:"peerCertSANs,omitempty"`
+}
+
+// ExternalEtcd describes an external etcd cluster.
+type ExternalEtcd struct {
+	// Endpoints of etcd members.
+	Endpoints []string `yaml:"endpoints"`
+	// CAFile is an SSL Certificate Authority file used to secure etcd communication.
+	CAFile string `yaml:"caFile"`
+	// CertFile is an SSL certification file used to secure etcd communication.
+	CertFile string `yaml:"certFile"`
+	// KeyFile is an SSL key file used to secure etcd communication.
+	KeyFile string `yaml:"keyFile"`
+}
+
+// Networking contains elements describing cluster's networking configuration
+type Networking struct {
+	// DNSDomain is the dns domain used by k8s services. Defaults to "cluster.local".
+	DNSDomain string `yaml:"dnsDomain,omitempty"`
+	// ServiceSubnet is the subnet used by k8s services. Defaults to "10.96.0.0/12".
+	ServiceSubnet string `yaml:"serviceSubnet,omitempty"`
+}
+
+type NodeRegistrationOptions struct {
+	Name string `yaml:"name,omitempty"`
+
+	// CRISocket is used to retrieve container runtime info. This information will be annotated to the Node API object, for later re-use
+	CRISocket string `yaml:"criSocket,omitempty"`
+}
@@ -0,0 +1,120 @@
+package v1alpha3
+
+import (
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/pytimer/certadm/pkg/kubeadm"
+)
+
+func init() {
+	kubeadm.Add("v1alpha3", NewKubeadmAlpha3())
+}
+
+type KubeadmAlpha3 struct {
+}
+
+func NewKubeadmAlpha3() *KubeadmAlpha3 {
+	return &KubeadmAlpha3{}
+}
+
+func (k *KubeadmAlpha3) RenewCertsCommandArgs() []string {
+	args := []string{
+		"alpha",
+		"phase",
+		"certs",
+		"all",
+	}
+	return args
+}
+
+// RenewCertCommandArgs returns nil, this kubeadm version can't renew a single certificate.
+func (k *KubeadmAlpha3) RenewCertCommandArgs(kubeadmVersion *version.Version, name string) []string {
+	return nil
+}
+
+// LoadConfig decodes the kubeadm documents of the API version and merges them into the Config,
+// the documents of the other API versions and kinds are ignored.
+func (k *KubeadmAlpha3) LoadConfig(docs []kubeadm.Document) (*kubeadm.Config, error) {
+	c := &kubeadm.Config{}
+	for _, d := range docs {
+		if d.APIVersion != kubeadm.GroupVersion("v1alpha3") {
+			continue
+		}
+
+		switch d.Kind {
+		case "InitConfiguration":
+			ic := &InitConfiguration{}
+			if err := yaml.Unmarshal(d.Data, ic); err != nil {
+				return nil, err
+			}
+			c.AdvertiseAddress = ic.APIEndpoint.AdvertiseAddress
+			c.BindPort = ic.APIEndpoint.BindPort
+			c.CRISocket = ic.NodeRegistration.CRISocket
+			c.NodeName = ic.NodeRegistration.Name
+		case "ClusterConfiguration":
+			cc := &ClusterConfiguration{}
+			if err := yaml.Unmarshal(d.Data, cc); err != nil {
+				return nil, err
+			}
+			c.KubernetesVersion = cc.KubernetesVersion
+			c.CertificatesDir = cc.CertificatesDir
+			c.APIServerCertSANs = cc.APIServerCertSANs
+			c.ControlPlaneEndpoint = cc.ControlPlaneEndpoint
+			c.DNSDomain = cc.Networking.DNSDomain
+			c.ServiceSubnet = cc.Networking.ServiceSubnet
+			if cc.Etcd.Local != nil {
+				c.EtcdServerCertSANs = cc.Etcd.Local.ServerCertSANs
+				c.EtcdPeerCertSANs = cc.Etcd.Local.PeerCertSANs
+			}
+			if cc.Etcd.External != nil {
+				c.ExternalEtcd = &kubeadm.ExternalEtcd{
+					Endpoints: cc.Etcd.External.Endpoints,
+					CAFile:    cc.Etcd.External.CAFile,
+					CertFile:  cc.Etcd.External.CertFile,
+					KeyFile:   cc.Etcd.External.KeyFile,
+				}
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// MarshalConfig encodes the Config as the InitConfiguration and ClusterConfiguration documents.
+func (k *KubeadmAlpha3) MarshalConfig(c *kubeadm.Config) ([]byte, error) {
+	ic := &InitConfiguration{
+		TypeMeta: kubeadm.TypeMeta{APIVersion: kubeadm.GroupVersion("v1alpha3"), Kind: "InitConfiguration"},
+		APIEndpoint: APIEndpoint{
+			AdvertiseAddress: c.AdvertiseAddress,
+			BindPort:         c.BindPort,
+		},
+		NodeRegistration: NodeRegistrationOptions{
+			Name:      c.NodeName,
+			CRISocket: c.CRISocket,
+		},
+	}
+	cc := &ClusterConfiguration{
+		TypeMeta:             kubeadm.TypeMeta{APIVersion: kubeadm.GroupVersion("v1alpha3"), Kind: "ClusterConfiguration"},
+		Networking:           Networking{DNSDomain: c.DNSDomain, ServiceSubnet: c.ServiceSubnet},
+		KubernetesVersion:    c.KubernetesVersion,
+		ControlPlaneEndpoint: c.ControlPlaneEndpoint,
+		APIServerCertSANs:    c.APIServerCertSANs,
+		CertificatesDir:      c.CertificatesDir,
+	}
+	if c.ExternalEtcd != nil {
+		cc.Etcd.External = &ExternalEtcd{
+			Endpoints: c.ExternalEtcd.Endpoints,
+			CAFile:    c.ExternalEtcd.CAFile,
+			CertFile:  c.ExternalEtcd.CertFile,
+			KeyFile:   c.ExternalEtcd.KeyFile,
+		}
+	} else if len(c.EtcdServerCertSANs) > 0 || len(c.EtcdPeerCertSANs) > 0 {
+		cc.Etcd.Local = &LocalEtcd{
+			ServerCertSANs: c.EtcdServerCertSANs,
+			PeerCertSANs:   c.EtcdPeerCertSANs,
+		}
+	}
+
+	return kubeadm.MarshalDocuments(ic, cc)
+}
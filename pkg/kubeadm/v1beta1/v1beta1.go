@@ -0,0 +1,134 @@
+package v1beta1
+
+import (
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/pytimer/certadm/pkg/kubeadm"
+)
+
+func init() {
+	kubeadm.Add("v1beta1", NewKubeadmBeta1("v1beta1", nil))
+}
+
+// KubeadmBeta1 is the Factory of the v1beta1 kubeadm config, it is shared by the later API versions whose
+// InitConfiguration and ClusterConfiguration fields used by certadm are the same, e.g. v1beta2, v1beta3 and v1beta4.
+type KubeadmBeta1 struct {
+	// version is the API version of the kubeadm config, e.g. v1beta3
+	version string
+	// renewCertCommandArgs returns the args to renew a single certificate by the kubeadm version, nil if kubeadm
+	// can't do it
+	renewCertCommandArgs func(kubeadmVersion *version.Version) []string
+}
+
+// NewKubeadmBeta1 returns the Factory of the kubeadm config of the API version, the args returned by the
+// renewCertCommandArgs are followed by the name to renew a single certificate or kubeconfig file, e.g.
+// kubeadm.CertsRenewCommandArgs, or nil if the kubeadm versions of the API version can't renew a single certificate.
+func NewKubeadmBeta1(version string, renewCertCommandArgs func(kubeadmVersion *version.Version) []string) *KubeadmBeta1 {
+	return &KubeadmBeta1{version: version, renewCertCommandArgs: renewCertCommandArgs}
+}
+
+func (k *KubeadmBeta1) RenewCertsCommandArgs() []string {
+	args := []string{
+		"init",
+		"phase",
+		"certs",
+		"all",
+	}
+	return args
+}
+
+// RenewCertCommandArgs returns the args of `kubeadm certs renew`, the name is the certificate or kubeconfig name,
+// e.g. apiserver or admin.conf. It returns nil if the kubeadm version can't renew a single certificate.
+func (k *KubeadmBeta1) RenewCertCommandArgs(kubeadmVersion *version.Version, name string) []string {
+	if k.renewCertCommandArgs == nil {
+		return nil
+	}
+	return append(k.renewCertCommandArgs(kubeadmVersion), name)
+}
+
+// LoadConfig decodes the kubeadm documents of the API version and merges them into the Config,
+// the documents of the other API versions and kinds are ignored.
+func (k *KubeadmBeta1) LoadConfig(docs []kubeadm.Document) (*kubeadm.Config, error) {
+	c := &kubeadm.Config{}
+	for _, d := range docs {
+		if d.APIVersion != kubeadm.GroupVersion(k.version) {
+			continue
+		}
+
+		switch d.Kind {
+		case "InitConfiguration":
+			ic := &InitConfiguration{}
+			if err := yaml.Unmarshal(d.Data, ic); err != nil {
+				return nil, err
+			}
+			c.AdvertiseAddress = ic.LocalAPIEndpoint.AdvertiseAddress
+			c.BindPort = ic.LocalAPIEndpoint.BindPort
+			c.CRISocket = ic.NodeRegistration.CRISocket
+			c.NodeName = ic.NodeRegistration.Name
+		case "ClusterConfiguration":
+			cc := &ClusterConfiguration{}
+			if err := yaml.Unmarshal(d.Data, cc); err != nil {
+				return nil, err
+			}
+			c.KubernetesVersion = cc.KubernetesVersion
+			c.CertificatesDir = cc.CertificatesDir
+			c.APIServerCertSANs = cc.APIServer.CertSANs
+			c.ControlPlaneEndpoint = cc.ControlPlaneEndpoint
+			c.DNSDomain = cc.Networking.DNSDomain
+			c.ServiceSubnet = cc.Networking.ServiceSubnet
+			if cc.Etcd.Local != nil {
+				c.EtcdServerCertSANs = cc.Etcd.Local.ServerCertSANs
+				c.EtcdPeerCertSANs = cc.Etcd.Local.PeerCertSANs
+			}
+			if cc.Etcd.External != nil {
+				c.ExternalEtcd = &kubeadm.ExternalEtcd{
+					Endpoints: cc.Etcd.External.Endpoints,
+					CAFile:    cc.Etcd.External.CAFile,
+					CertFile:  cc.Etcd.External.CertFile,
+					KeyFile:   cc.Etcd.External.KeyFile,
+				}
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// MarshalConfig encodes the Config as the InitConfiguration and ClusterConfiguration documents.
+func (k *KubeadmBeta1) MarshalConfig(c *kubeadm.Config) ([]byte, error) {
+	ic := &InitConfiguration{
+		TypeMeta: kubeadm.TypeMeta{APIVersion: kubeadm.GroupVersion(k.version), Kind: "InitConfiguration"},
+		LocalAPIEndpoint: APIEndpoint{
+			AdvertiseAddress: c.AdvertiseAddress,
+			BindPort:         c.BindPort,
+		},
+		NodeRegistration: NodeRegistrationOptions{
+			Name:      c.NodeName,
+			CRISocket: c.CRISocket,
+		},
+	}
+	cc := &ClusterConfiguration{
+		TypeMeta:             kubeadm.TypeMeta{APIVersion: kubeadm.GroupVersion(k.version), Kind: "ClusterConfiguration"},
+		Networking:           Networking{DNSDomain: c.DNSDomain, ServiceSubnet: c.ServiceSubnet},
+		KubernetesVersion:    c.KubernetesVersion,
+		ControlPlaneEndpoint: c.ControlPlaneEndpoint,
+		APIServer:            APIServer{CertSANs: c.APIServerCertSANs},
+		CertificatesDir:      c.CertificatesDir,
+	}
+	if c.ExternalEtcd != nil {
+		cc.Etcd.External = &ExternalEtcd{
+			Endpoints: c.ExternalEtcd.Endpoints,
+			CAFile:    c.ExternalEtcd.CAFile,
+			CertFile:  c.ExternalEtcd.CertFile,
+			KeyFile:   c.ExternalEtcd.KeyFile,
+		}
+	} else if len(c.EtcdServerCertSANs) > 0 || len(c.EtcdPeerCertSANs) > 0 {
+		cc.Etcd.Local = &LocalEtcd{
+			ServerCertSANs: c.EtcdServerCertSANs,
+			PeerCertSANs:   c.EtcdPeerCertSANs,
+		}
+	}
+
+	return kubeadm.MarshalDocuments(ic, cc)
+}
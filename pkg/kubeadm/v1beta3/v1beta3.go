@@ -0,0 +1,11 @@
+package v1beta3
+
+import (
+	"github.com/pytimer/certadm/pkg/kubeadm"
+	"github.com/pytimer/certadm/pkg/kubeadm/v1beta1"
+)
+
+// init registers the v1beta3 kubeadm config, whose fields used by certadm are the same as v1beta1.
+func init() {
+	kubeadm.Add("v1beta3", v1beta1.NewKubeadmBeta1("v1beta3", kubeadm.CertsRenewCommandArgs))
+}
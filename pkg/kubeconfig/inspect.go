@@ -0,0 +1,99 @@
+package kubeconfig
+
+import (
+	"crypto/x509"
+	"path/filepath"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+// ListCertificates returns the client certificates embedded in or referred by the kubeconfig files.
+// The kubeconfig file which not exists or has no client certificate will be skipped.
+func ListCertificates(kubeconfigDir string) ([]*certs.CertificateInfo, error) {
+	infos := []*certs.CertificateInfo{}
+	for _, kf := range KubeConfigFiles {
+		kubeconfigPath := filepath.Join(kubeconfigDir, kf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, kubeconfigPath); err != nil {
+			return nil, err
+		} else if !exists {
+			klog.Warningf("[kubeconfig] kubeconfig %s not exists, skip it", kubeconfigPath)
+			continue
+		}
+
+		cert, err := LoadClientCertificate(kubeconfigPath)
+		if err != nil {
+			return nil, err
+		}
+		if cert == nil {
+			klog.Warningf("[kubeconfig] kubeconfig %s has no client certificate, skip it", kubeconfigPath)
+			continue
+		}
+		infos = append(infos, certs.NewCertificateInfo(kf, kubeconfigPath, cert))
+	}
+	return infos, nil
+}
+
+// LoadClientCertificate returns the client certificate embedded in the kubeconfig file, or in the file referred by
+// the kubeconfig file, e.g. the kubelet-client-current.pem of kubelet.conf. It returns nil if the kubeconfig file
+// has no client certificate.
+func LoadClientCertificate(kubeconfigPath string) (*x509.Certificate, error) {
+	c, err := LoadFromFile(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	authInfo, err := GetCurrentAuthInfo(c)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get user from %s", kubeconfigPath)
+	}
+	if authInfo.ClientCertificateData == "" {
+		if authInfo.ClientCertificate == "" {
+			return nil, nil
+		}
+		cs, err := pkiutil.CertsFromFile(resolvePath(kubeconfigPath, authInfo.ClientCertificate))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load the client certificate referred by %s", kubeconfigPath)
+		}
+		return cs[0], nil
+	}
+
+	data, err := decodeData(authInfo.ClientCertificateData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the client certificate in %s", kubeconfigPath)
+	}
+	cs, err := pkiutil.ParseCertsPEM(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the client certificate in %s", kubeconfigPath)
+	}
+	return cs[0], nil
+}
+
+// ClientCertificateFile returns the client certificate file referred by the kubeconfig file, it returns empty
+// if the kubeconfig file embeds the client certificate.
+func ClientCertificateFile(kubeconfigPath string) (string, error) {
+	c, err := LoadFromFile(kubeconfigPath)
+	if err != nil {
+		return "", err
+	}
+	authInfo, err := GetCurrentAuthInfo(c)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get user from %s", kubeconfigPath)
+	}
+	if authInfo.ClientCertificateData != "" || authInfo.ClientCertificate == "" {
+		return "", nil
+	}
+	return resolvePath(kubeconfigPath, authInfo.ClientCertificate), nil
+}
+
+// resolvePath resolves the relative path in the kubeconfig file against the directory of the kubeconfig file,
+// the same as kubectl.
+func resolvePath(kubeconfigPath, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(filepath.Dir(kubeconfigPath), p)
+}
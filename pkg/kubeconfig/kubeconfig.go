@@ -5,56 +5,37 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/pytimer/certadm/pkg/kubeadm"
-
-	"github.com/otiai10/copy"
-	"k8s.io/klog"
 	"k8s.io/utils/path"
 )
 
-var kubeConfigs = []string{
+// KubeConfigFiles is the list of the kubeconfig files created by kubeadm.
+var KubeConfigFiles = []string{
 	"admin.conf",
 	"controller-manager.conf",
 	"scheduler.conf",
 	"kubelet.conf",
 }
 
-func RemoveOldKubeconfig(kubeconfigDir string) error {
-	for _, kf := range kubeConfigs {
+// ListKubeConfigFiles returns the existing kubeconfig files in the kubeconfigDir.
+func ListKubeConfigFiles(kubeconfigDir string) ([]string, error) {
+	files := []string{}
+	for _, kf := range KubeConfigFiles {
 		kubeconfigPath := filepath.Join(kubeconfigDir, kf)
 		if exists, err := path.Exists(path.CheckFollowSymlink, kubeconfigPath); err != nil {
-			continue
+			return nil, err
 		} else if !exists {
 			continue
 		}
-		if err := os.Remove(kubeconfigPath); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func RenewKubeConfigFile(conf string) error {
-	if exists, err := path.Exists(path.CheckFollowSymlink, conf); err != nil {
-		return err
-	} else if !exists {
-		return fmt.Errorf("config file: %s not exists", conf)
+		files = append(files, kubeconfigPath)
 	}
-
-	out, err := kubeadm.PhasesCreateKubeConfig(conf)
-	klog.Info(string(out))
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return files, nil
 }
 
-func CreateKubectlKubeConfig(kubernetesDir string) error {
+// KubectlKubeConfigPath returns the default kubeconfig path of kubectl.
+func KubectlKubeConfigPath() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		homeDir = "/root"
 	}
-	kubectlDir := fmt.Sprintf("%s/.kube/config", homeDir)
-	return copy.Copy(filepath.Join(kubernetesDir, "admin.conf"), kubectlDir)
+	return fmt.Sprintf("%s/.kube/config", homeDir)
 }
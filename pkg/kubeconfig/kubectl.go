@@ -0,0 +1,225 @@
+package kubeconfig
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/util"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+// MergeKubectlKubeConfig merges the admin credentials of admin.conf in the kubernetesDir into the kubectl kubeconfig
+// file. The users whose client certificates have the subject of the admin.conf client certificate and are signed by
+// the cluster CA get the renewed client certificate and key, the clusters trusting the cluster CA get the CA
+// certificates of admin.conf, and the other clusters, users and contexts are untouched. The admin.conf entries are
+// added if no user matches. The original file is backed up and the backup path is returned, admin.conf is copied
+// if the kubectl kubeconfig file not exists.
+func MergeKubectlKubeConfig(kubernetesDir, kubectlConfigPath string) (string, error) {
+	adminConfPath := filepath.Join(kubernetesDir, "admin.conf")
+	admin, err := LoadFromFile(adminConfPath)
+	if err != nil {
+		return "", err
+	}
+
+	if exists, err := path.Exists(path.CheckFollowSymlink, kubectlConfigPath); err != nil {
+		return "", err
+	} else if !exists {
+		klog.Infof("[kubeconfig] %s not exists, copy %s to it", kubectlConfigPath, adminConfPath)
+		if err := os.MkdirAll(filepath.Dir(kubectlConfigPath), 0700); err != nil {
+			return "", err
+		}
+		return "", WriteToFile(admin, kubectlConfigPath)
+	}
+
+	adminCluster, err := GetCurrentCluster(admin)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get cluster from %s", adminConfPath)
+	}
+	caCerts, err := clusterCACerts(adminConfPath, adminCluster)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load the CA certificates in %s", adminConfPath)
+	}
+	if len(caCerts) == 0 {
+		return "", errors.Errorf("%s does not embed the CA certificate", adminConfPath)
+	}
+	adminAuthInfo, err := GetCurrentAuthInfo(admin)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get user from %s", adminConfPath)
+	}
+	adminCert, err := authInfoClientCert(adminAuthInfo)
+	if err != nil || adminCert == nil {
+		return "", errors.Errorf("%s does not embed a valid client certificate: %v", adminConfPath, err)
+	}
+
+	original, err := ioutil.ReadFile(kubectlConfigPath)
+	if err != nil {
+		return "", err
+	}
+	c, err := LoadFromFile(kubectlConfigPath)
+	if err != nil {
+		return "", err
+	}
+
+	changed := false
+	for i := range c.Clusters {
+		cluster := &c.Clusters[i].Cluster
+		certs, err := clusterCACerts(kubectlConfigPath, cluster)
+		if err != nil {
+			klog.V(1).Infof("[kubeconfig] failed to load the CA certificates of the cluster %s in %s, skip it: %v", c.Clusters[i].Name, kubectlConfigPath, err)
+			continue
+		}
+		if !containsCert(caCerts, certs) || cluster.CertificateAuthorityData == "" ||
+			cluster.CertificateAuthorityData == adminCluster.CertificateAuthorityData {
+			continue
+		}
+		cluster.CertificateAuthorityData = adminCluster.CertificateAuthorityData
+		klog.Infof("[kubeconfig] Updated the CA certificates of the cluster %s in %s", c.Clusters[i].Name, kubectlConfigPath)
+		changed = true
+	}
+
+	matched := false
+	for i := range c.AuthInfos {
+		authInfo := &c.AuthInfos[i].AuthInfo
+		cert, err := authInfoClientCert(authInfo)
+		if err != nil || cert == nil || cert.Subject.String() != adminCert.Subject.String() || !signedBy(cert, caCerts) {
+			continue
+		}
+		matched = true
+		if authInfo.ClientCertificateData == adminAuthInfo.ClientCertificateData && authInfo.ClientKeyData == adminAuthInfo.ClientKeyData {
+			continue
+		}
+		authInfo.ClientCertificateData = adminAuthInfo.ClientCertificateData
+		authInfo.ClientKeyData = adminAuthInfo.ClientKeyData
+		klog.Infof("[kubeconfig] Updated the client certificate of the user %s in %s", c.AuthInfos[i].Name, kubectlConfigPath)
+		changed = true
+	}
+
+	if !matched {
+		added, err := addAdminEntries(c, admin)
+		if err != nil {
+			klog.Warningf("[kubeconfig] %s has no admin user of the cluster, and the admin.conf entries can't be added: %v, please merge it manually", kubectlConfigPath, err)
+		} else if added {
+			klog.Infof("[kubeconfig] Added the cluster, user and context of %s to %s", adminConfPath, kubectlConfigPath)
+			changed = true
+		}
+	}
+	if !changed {
+		return "", nil
+	}
+
+	backupPath := fmt.Sprintf("%s.%s.bak", kubectlConfigPath, time.Now().Format("20060102150405"))
+	if err := util.WriteFileAtomic(backupPath, original, 0600); err != nil {
+		return "", errors.Wrapf(err, "failed to back up %s", kubectlConfigPath)
+	}
+	if err := WriteToFile(c, kubectlConfigPath); err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// addAdminEntries adds the current cluster, user and context of admin.conf to the kubeconfig, it returns an error
+// if any of the names is used by the kubeconfig.
+func addAdminEntries(c, admin *Config) (bool, error) {
+	var context *NamedContext
+	for i := range admin.Contexts {
+		if admin.Contexts[i].Name == admin.CurrentContext {
+			context = &admin.Contexts[i]
+		}
+	}
+	if context == nil {
+		return false, errors.Errorf("admin.conf has no current context %q", admin.CurrentContext)
+	}
+
+	for _, ctx := range c.Contexts {
+		if ctx.Name == context.Name {
+			return false, errors.Errorf("the context %s exists", ctx.Name)
+		}
+	}
+	for _, cluster := range c.Clusters {
+		if cluster.Name == context.Context.Cluster {
+			return false, errors.Errorf("the cluster %s exists", cluster.Name)
+		}
+	}
+	for _, authInfo := range c.AuthInfos {
+		if authInfo.Name == context.Context.AuthInfo {
+			return false, errors.Errorf("the user %s exists", authInfo.Name)
+		}
+	}
+
+	for _, cluster := range admin.Clusters {
+		if cluster.Name == context.Context.Cluster {
+			c.Clusters = append(c.Clusters, cluster)
+		}
+	}
+	for _, authInfo := range admin.AuthInfos {
+		if authInfo.Name == context.Context.AuthInfo {
+			c.AuthInfos = append(c.AuthInfos, authInfo)
+		}
+	}
+	c.Contexts = append(c.Contexts, *context)
+	if c.CurrentContext == "" {
+		c.CurrentContext = context.Name
+	}
+	return true, nil
+}
+
+// clusterCACerts returns the CA certificates embedded in the cluster of the kubeconfigPath or referred by it,
+// the relative path is resolved against the directory of the kubeconfigPath as kubectl does.
+func clusterCACerts(kubeconfigPath string, cluster *Cluster) ([]*x509.Certificate, error) {
+	if cluster.CertificateAuthorityData != "" {
+		data, err := decodeData(cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, err
+		}
+		return pkiutil.ParseCertsPEM(data)
+	}
+	if cluster.CertificateAuthority != "" {
+		return pkiutil.CertsFromFile(resolvePath(kubeconfigPath, cluster.CertificateAuthority))
+	}
+	return nil, nil
+}
+
+// authInfoClientCert returns the client certificate embedded in the user, or nil if it is not embedded.
+func authInfoClientCert(authInfo *AuthInfo) (*x509.Certificate, error) {
+	if authInfo.ClientCertificateData == "" {
+		return nil, nil
+	}
+	data, err := decodeData(authInfo.ClientCertificateData)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := pkiutil.ParseCertsPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+func containsCert(certs, targets []*x509.Certificate) bool {
+	for _, cert := range certs {
+		for _, target := range targets {
+			if bytes.Equal(cert.Raw, target.Raw) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func signedBy(cert *x509.Certificate, caCerts []*x509.Certificate) bool {
+	for _, ca := range caCerts {
+		if cert.CheckSignatureFrom(ca) == nil {
+			return true
+		}
+	}
+	return false
+}
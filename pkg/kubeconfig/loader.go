@@ -0,0 +1,93 @@
+package kubeconfig
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+
+	"github.com/pytimer/certadm/pkg/util"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// LoadFromFile takes a filename and deserializes the contents into Config object
+func LoadFromFile(filename string) (*Config, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode kubeconfig %s", filename)
+	}
+	return c, nil
+}
+
+// Encode serializes the config to yaml.
+func Encode(c *Config) ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// WriteToFile serializes the config to yaml and writes it out to a file.
+func WriteToFile(c *Config, filename string) error {
+	b, err := Encode(c)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(filename, b, 0600)
+}
+
+// GetCurrentAuthInfo returns the AuthInfo used by the current context. The only AuthInfo is returned when
+// the current context is not set, and it returns an error rather than guessing if there are more.
+func GetCurrentAuthInfo(c *Config) (*AuthInfo, error) {
+	if c.CurrentContext == "" {
+		if len(c.AuthInfos) == 1 {
+			return &c.AuthInfos[0].AuthInfo, nil
+		}
+		return nil, errors.Errorf("the current context is not set and there are %d users", len(c.AuthInfos))
+	}
+
+	userName := ""
+	found := false
+	for _, ctx := range c.Contexts {
+		if ctx.Name == c.CurrentContext {
+			userName = ctx.Context.AuthInfo
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("the current context %q not exists", c.CurrentContext)
+	}
+
+	for i := range c.AuthInfos {
+		if c.AuthInfos[i].Name == userName {
+			return &c.AuthInfos[i].AuthInfo, nil
+		}
+	}
+	return nil, errors.Errorf("no user %q found for the current context %q", userName, c.CurrentContext)
+}
+
+// GetCurrentCluster returns the Cluster used by the current context, or the first Cluster
+// when the current context is not set.
+func GetCurrentCluster(c *Config) (*Cluster, error) {
+	clusterName := ""
+	for _, ctx := range c.Contexts {
+		if ctx.Name == c.CurrentContext {
+			clusterName = ctx.Context.Cluster
+			break
+		}
+	}
+
+	for i := range c.Clusters {
+		if clusterName == "" || c.Clusters[i].Name == clusterName {
+			return &c.Clusters[i].Cluster, nil
+		}
+	}
+	return nil, errors.Errorf("no cluster found for the current context %q", c.CurrentContext)
+}
+
+func decodeData(data string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(data)
+}
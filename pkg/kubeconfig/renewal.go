@@ -0,0 +1,206 @@
+package kubeconfig
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"path/filepath"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+// RenewKubeConfigFiles renews the client certificates embedded in the kubeconfig files selected by
+// the RenewOptions in-process.
+func RenewKubeConfigFiles(kubeconfigDir, certDir string, o *certs.RenewOptions) error {
+	if o == nil {
+		o = &certs.RenewOptions{}
+	}
+	for _, kf := range KubeConfigFiles {
+		if !certs.Selected(o.Names, kf) {
+			continue
+		}
+		kubeconfigPath := filepath.Join(kubeconfigDir, kf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, kubeconfigPath); err != nil {
+			return err
+		} else if !exists {
+			klog.Warningf("[kubeconfig] kubeconfig %s not exists, skip it", kubeconfigPath)
+			continue
+		}
+
+		if err := RenewClientCertificate(kubeconfigPath, certDir, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenewClientCertificate re-issues the client certificate embedded in the kubeconfig file
+// with the cluster CA in the certDir. The embedded private key is reused unless the RotateKeys is true,
+// the validity is looked up by the kubeconfig file name, e.g. admin.conf.
+func RenewClientCertificate(kubeconfigPath, certDir string, o *certs.RenewOptions) error {
+	if o == nil {
+		o = &certs.RenewOptions{}
+	}
+
+	c, err := LoadFromFile(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	authInfo, err := GetCurrentAuthInfo(c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get user from %s", kubeconfigPath)
+	}
+	if authInfo.ClientCertificateData == "" {
+		if authInfo.ClientCertificate != "" {
+			klog.Infof("[kubeconfig] kubeconfig %s refers to the client certificate %s, skip it", kubeconfigPath, authInfo.ClientCertificate)
+			return nil
+		}
+		klog.Warningf("[kubeconfig] kubeconfig %s does not embed the client certificate, skip it", kubeconfigPath)
+		return nil
+	}
+
+	data, err := decodeData(authInfo.ClientCertificateData)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode the client certificate in %s", kubeconfigPath)
+	}
+	clientCerts, err := pkiutil.ParseCertsPEM(data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse the client certificate in %s", kubeconfigPath)
+	}
+
+	caCert, caKey, err := pkiutil.TryLoadCertAndKeyFromDisk(certDir, constants.CACertAndKeyBaseName)
+	if err != nil {
+		return errors.Wrap(err, "failed to load the cluster CA")
+	}
+
+	var key crypto.Signer
+	if o.RotateKeys {
+		key, err = pkiutil.NewPrivateKey()
+		if err != nil {
+			return errors.Wrapf(err, "failed to create the private key for %s", kubeconfigPath)
+		}
+	} else {
+		keyData, err := decodeData(authInfo.ClientKeyData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode the client key in %s", kubeconfigPath)
+		}
+		key, err = pkiutil.ParsePrivateKeyPEM(keyData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse the client key in %s", kubeconfigPath)
+		}
+	}
+	name := filepath.Base(kubeconfigPath)
+	validity := o.Validity.For(name)
+	newCert, err := pkiutil.RenewSignedCert(clientCerts[0], key, caCert, caKey, validity)
+	if err != nil {
+		return errors.Wrapf(err, "failed to sign the client certificate for %s", kubeconfigPath)
+	}
+	certs.WarnIfCapped(name, newCert, caCert, validity)
+	keyPEM, err := pkiutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return err
+	}
+
+	authInfo.ClientCertificateData = base64.StdEncoding.EncodeToString(pkiutil.EncodeCertPEM(newCert))
+	authInfo.ClientKeyData = base64.StdEncoding.EncodeToString(keyPEM)
+
+	if err := WriteToFile(c, kubeconfigPath); err != nil {
+		return err
+	}
+	klog.Infof("[kubeconfig] Renewed the client certificate in %s, expires on %s", kubeconfigPath, newCert.NotAfter)
+	return nil
+}
+
+// ValidateKubeConfigFiles checks the client certificates embedded in the kubeconfig files in the names,
+// or all of them if the names is empty, match the private keys and are signed by the cluster CA in the certDir.
+func ValidateKubeConfigFiles(kubeconfigDir, certDir string, names []string) error {
+	caCert, err := pkiutil.TryLoadCertFromDisk(certDir, constants.CACertAndKeyBaseName)
+	if err != nil {
+		return errors.Wrap(err, "failed to load the cluster CA")
+	}
+
+	for _, kf := range KubeConfigFiles {
+		if !certs.Selected(names, kf) {
+			continue
+		}
+		kubeconfigPath := filepath.Join(kubeconfigDir, kf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, kubeconfigPath); err != nil {
+			return err
+		} else if !exists {
+			continue
+		}
+
+		c, err := LoadFromFile(kubeconfigPath)
+		if err != nil {
+			return err
+		}
+		authInfo, err := GetCurrentAuthInfo(c)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get user from %s", kubeconfigPath)
+		}
+		if authInfo.ClientCertificateData == "" {
+			continue
+		}
+
+		certData, err := decodeData(authInfo.ClientCertificateData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode the client certificate in %s", kubeconfigPath)
+		}
+		clientCerts, err := pkiutil.ParseCertsPEM(certData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse the client certificate in %s", kubeconfigPath)
+		}
+		keyData, err := decodeData(authInfo.ClientKeyData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode the client key in %s", kubeconfigPath)
+		}
+		key, err := pkiutil.ParsePrivateKeyPEM(keyData)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse the client key in %s", kubeconfigPath)
+		}
+		if err := pkiutil.VerifyCertAndKey(clientCerts[0], key, caCert); err != nil {
+			return errors.Wrapf(err, "the client certificate in %s is invalid", kubeconfigPath)
+		}
+	}
+	return nil
+}
+
+// SetCertificateAuthorities replaces the CA certificates embedded in the current cluster of the kubeconfig files
+// with the trust bundle of the cluster CA. The kubeconfig file which refers to the CA file is skipped.
+func SetCertificateAuthorities(kubeconfigDir string, caCerts []*x509.Certificate) error {
+	for _, kf := range KubeConfigFiles {
+		kubeconfigPath := filepath.Join(kubeconfigDir, kf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, kubeconfigPath); err != nil {
+			return err
+		} else if !exists {
+			continue
+		}
+
+		c, err := LoadFromFile(kubeconfigPath)
+		if err != nil {
+			return err
+		}
+		cluster, err := GetCurrentCluster(c)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get cluster from %s", kubeconfigPath)
+		}
+		if cluster.CertificateAuthorityData == "" {
+			klog.Warningf("[kubeconfig] kubeconfig %s does not embed the CA certificate, skip it", kubeconfigPath)
+			continue
+		}
+
+		cluster.CertificateAuthorityData = base64.StdEncoding.EncodeToString(pkiutil.EncodeCertsPEM(caCerts))
+		if err := WriteToFile(c, kubeconfigPath); err != nil {
+			return err
+		}
+		klog.Infof("[kubeconfig] Updated the CA certificates in %s, %d certificates are trusted", kubeconfigPath, len(caCerts))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package kubeconfig
+
+// The types below hold the fields certadm reads or writes, the other fields, e.g. preferences, extensions,
+// insecure-skip-tls-verify or exec, are kept in the inline Extra maps, so the kubeconfig files are rewritten
+// without losing them.
+
+// Config holds the information needed to build connect to remote kubernetes clusters as a given user
+type Config struct {
+	APIVersion     string                 `yaml:"apiVersion,omitempty"`
+	Kind           string                 `yaml:"kind,omitempty"`
+	Clusters       []NamedCluster         `yaml:"clusters"`
+	AuthInfos      []NamedAuthInfo        `yaml:"users"`
+	Contexts       []NamedContext         `yaml:"contexts"`
+	CurrentContext string                 `yaml:"current-context"`
+	Extra          map[string]interface{} `yaml:",inline"`
+}
+
+// NamedCluster relates nicknames to cluster information
+type NamedCluster struct {
+	Name    string                 `yaml:"name"`
+	Cluster Cluster                `yaml:"cluster"`
+	Extra   map[string]interface{} `yaml:",inline"`
+}
+
+// Cluster contains information about how to communicate with a kubernetes cluster
+type Cluster struct {
+	Server                   string                 `yaml:"server"`
+	CertificateAuthority     string                 `yaml:"certificate-authority,omitempty"`
+	CertificateAuthorityData string                 `yaml:"certificate-authority-data,omitempty"`
+	Extra                    map[string]interface{} `yaml:",inline"`
+}
+
+// NamedAuthInfo relates nicknames to auth information
+type NamedAuthInfo struct {
+	Name     string                 `yaml:"name"`
+	AuthInfo AuthInfo               `yaml:"user"`
+	Extra    map[string]interface{} `yaml:",inline"`
+}
+
+// AuthInfo contains information that describes identity information.
+type AuthInfo struct {
+	ClientCertificate     string                 `yaml:"client-certificate,omitempty"`
+	ClientCertificateData string                 `yaml:"client-certificate-data,omitempty"`
+	ClientKey             string                 `yaml:"client-key,omitempty"`
+	ClientKeyData         string                 `yaml:"client-key-data,omitempty"`
+	Extra                 map[string]interface{} `yaml:",inline"`
+}
+
+// NamedContext relates nicknames to context information
+type NamedContext struct {
+	Name    string                 `yaml:"name"`
+	Context Context                `yaml:"context"`
+	Extra   map[string]interface{} `yaml:",inline"`
+}
+
+// Context is a tuple of references to a cluster, a user and a namespace
+type Context struct {
+	Cluster   string                 `yaml:"cluster"`
+	AuthInfo  string                 `yaml:"user"`
+	Namespace string                 `yaml:"namespace,omitempty"`
+	Extra     map[string]interface{} `yaml:",inline"`
+}
@@ -0,0 +1,135 @@
+package kubeconfig
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+// UserOptions is the user of the kubeconfig created by CreateUserKubeConfig.
+type UserOptions struct {
+	// Name is the common name of the client certificate, which is the user name in Kubernetes
+	Name string
+	// Organizations is the organizations of the client certificate, which are the groups in Kubernetes
+	Organizations []string
+	// Validity is the validity period of the client certificate, it is capped at the expiration of the CA
+	Validity time.Duration
+	// Server is the API server URL, defaults to the server of admin.conf
+	Server string
+}
+
+// CreateUserKubeConfig creates a kubeconfig which embeds a new private key, a client certificate of the user signed
+// by the cluster CA in the certDir and the trust bundle of the cluster CA. The cluster name and the server default
+// to the ones of admin.conf in the kubeconfigDir.
+func CreateUserKubeConfig(kubeconfigDir, certDir string, o *UserOptions) (*Config, error) {
+	if o.Name == "" {
+		return nil, errors.New("the user name is required")
+	}
+
+	clusterName, server, err := adminCluster(kubeconfigDir)
+	if err != nil {
+		return nil, err
+	}
+	if o.Server != "" {
+		server = o.Server
+	}
+	if server == "" {
+		return nil, errors.New("the API server URL is required, admin.conf not exists or has no server")
+	}
+
+	caCerts, err := pkiutil.TryLoadCertsFromDisk(certDir, constants.CACertAndKeyBaseName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the cluster CA")
+	}
+	caCert, caKey, err := pkiutil.TryLoadCertAndKeyFromDisk(certDir, constants.CACertAndKeyBaseName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the cluster CA")
+	}
+
+	key, err := pkiutil.NewPrivateKey()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create the private key for the user %s", o.Name)
+	}
+	tmpl := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:   o.Name,
+			Organization: o.Organizations,
+		},
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := pkiutil.RenewSignedCert(tmpl, key, caCert, caKey, o.Validity)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to sign the client certificate for the user %s", o.Name)
+	}
+	certs.WarnIfCapped(o.Name, cert, caCert, o.Validity)
+	keyPEM, err := pkiutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("[kubeconfig] Signed the client certificate of the user %s in the groups %v, expires on %s", o.Name, o.Organizations, cert.NotAfter)
+
+	contextName := fmt.Sprintf("%s@%s", o.Name, clusterName)
+	return &Config{
+		APIVersion: "v1",
+		Kind:       "Config",
+		Clusters: []NamedCluster{{
+			Name: clusterName,
+			Cluster: Cluster{
+				Server:                   server,
+				CertificateAuthorityData: base64.StdEncoding.EncodeToString(pkiutil.EncodeCertsPEM(caCerts)),
+			},
+		}},
+		AuthInfos: []NamedAuthInfo{{
+			Name: o.Name,
+			AuthInfo: AuthInfo{
+				ClientCertificateData: base64.StdEncoding.EncodeToString(pkiutil.EncodeCertPEM(cert)),
+				ClientKeyData:         base64.StdEncoding.EncodeToString(keyPEM),
+			},
+		}},
+		Contexts: []NamedContext{{
+			Name: contextName,
+			Context: Context{
+				Cluster:  clusterName,
+				AuthInfo: o.Name,
+			},
+		}},
+		CurrentContext: contextName,
+	}, nil
+}
+
+// adminCluster returns the name and the server of the current cluster of admin.conf, the name defaults to
+// kubernetes and the server is empty if admin.conf not exists.
+func adminCluster(kubeconfigDir string) (string, string, error) {
+	adminConfPath := filepath.Join(kubeconfigDir, "admin.conf")
+	if exists, err := path.Exists(path.CheckFollowSymlink, adminConfPath); err != nil || !exists {
+		return "kubernetes", "", err
+	}
+
+	c, err := LoadFromFile(adminConfPath)
+	if err != nil {
+		return "", "", err
+	}
+	for _, ctx := range c.Contexts {
+		if ctx.Name != c.CurrentContext {
+			continue
+		}
+		for _, cluster := range c.Clusters {
+			if cluster.Name == ctx.Context.Cluster {
+				return cluster.Name, cluster.Cluster.Server, nil
+			}
+		}
+	}
+	return "kubernetes", "", nil
+}
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/output/v1alpha1"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// TableOutput prints the human readable table
+	TableOutput = "table"
+	// JSONOutput prints the versioned object as JSON
+	JSONOutput = "json"
+	// YAMLOutput prints the versioned object as YAML
+	YAMLOutput = "yaml"
+)
+
+// ValidateFormat returns an error if the output format is not supported.
+func ValidateFormat(format string) error {
+	switch format {
+	case TableOutput, JSONOutput, YAMLOutput:
+		return nil
+	}
+	return errors.Errorf("unsupported output format %q, supported formats: %s|%s|%s", format, JSONOutput, YAMLOutput, TableOutput)
+}
+
+// PrintCertificates prints the certificates status in the output format.
+func PrintCertificates(out io.Writer, format string, infos []*certs.CertificateInfo) error {
+	switch format {
+	case TableOutput:
+		return printCertificatesTable(out, infos)
+	case JSONOutput:
+		b, err := json.MarshalIndent(toCertificateExpirationInfo(infos), "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(b))
+		return err
+	case YAMLOutput:
+		b, err := yaml.Marshal(toCertificateExpirationInfo(infos))
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(b)
+		return err
+	}
+	return ValidateFormat(format)
+}
+
+func toCertificateExpirationInfo(infos []*certs.CertificateInfo) *v1alpha1.CertificateExpirationInfo {
+	certificates := []v1alpha1.Certificate{}
+	for _, info := range infos {
+		certificates = append(certificates, v1alpha1.Certificate{
+			Name:                 info.Name,
+			Path:                 info.Path,
+			Subject:              info.Subject,
+			Issuer:               info.Issuer,
+			ExpirationDate:       info.NotAfter,
+			ResidualDays:         info.ResidualDays,
+			CertificateAuthority: info.IsCA,
+		})
+	}
+	return v1alpha1.NewCertificateExpirationInfo(certificates)
+}
+
+func printCertificatesTable(out io.Writer, infos []*certs.CertificateInfo) error {
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "CERTIFICATE\tSUBJECT\tISSUER\tEXPIRES\tRESIDUAL DAYS\tCA")
+	for _, info := range infos {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%t\n",
+			info.Name,
+			info.Subject,
+			info.Issuer,
+			info.NotAfter.Format("Jan 02, 2006 15:04 MST"),
+			info.ResidualDays,
+			info.IsCA,
+		)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,48 @@
+package v1alpha1
+
+import "time"
+
+const (
+	// GroupName is the group name of the certadm output API
+	GroupName = "output.certadm.pytimer.github.com"
+
+	// SchemeGroupVersion is group version used to output the certadm status
+	SchemeGroupVersion = GroupName + "/v1alpha1"
+
+	// CertificateExpirationInfoKind is the kind of the CertificateExpirationInfo
+	CertificateExpirationInfoKind = "CertificateExpirationInfo"
+)
+
+// CertificateExpirationInfo is the status of the certificates and kubeconfig on the node.
+type CertificateExpirationInfo struct {
+	Kind       string `json:"kind" yaml:"kind"`
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+
+	// Certificates holds the status of every certificate.
+	Certificates []Certificate `json:"certificates" yaml:"certificates"`
+}
+
+// Certificate is the status of a certificate.
+type Certificate struct {
+	// Name is the name of the certificate, e.g. apiserver, etcd/server or admin.conf
+	Name string `json:"name" yaml:"name"`
+	// Path is the file which contains the certificate.
+	Path    string `json:"path" yaml:"path"`
+	Subject string `json:"subject" yaml:"subject"`
+	Issuer  string `json:"issuer" yaml:"issuer"`
+	// ExpirationDate is the NotAfter of the certificate.
+	ExpirationDate time.Time `json:"expirationDate" yaml:"expirationDate"`
+	// ResidualDays is the days before the certificate expires.
+	ResidualDays int `json:"residualDays" yaml:"residualDays"`
+	// CertificateAuthority is true when the certificate is a CA.
+	CertificateAuthority bool `json:"certificateAuthority" yaml:"certificateAuthority"`
+}
+
+// NewCertificateExpirationInfo returns the CertificateExpirationInfo with the kind and apiVersion.
+func NewCertificateExpirationInfo(certificates []Certificate) *CertificateExpirationInfo {
+	return &CertificateExpirationInfo{
+		Kind:         CertificateExpirationInfoKind,
+		APIVersion:   SchemeGroupVersion,
+		Certificates: certificates,
+	}
+}
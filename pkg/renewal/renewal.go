@@ -0,0 +1,366 @@
+package renewal
+
+import (
+	"path/filepath"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/config"
+	"github.com/pytimer/certadm/pkg/kubeadm"
+	"github.com/pytimer/certadm/pkg/kubeconfig"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+)
+
+const (
+	// NativeBackend renews the certificates in-process with crypto/x509
+	NativeBackend = "native"
+	// KubeadmBackend renews the certificates by the kubeadm binary
+	KubeadmBackend = "kubeadm"
+)
+
+// Renewer renews the Kubernetes certificates and the kubeconfig files.
+type Renewer interface {
+	RenewCertificates() error
+	RenewKubeConfigs() error
+	// Plan returns the changes of the renewal without touching the disk.
+	Plan() (*Plan, error)
+	// SANDiffs returns the SANs of the existing and the renewed certificates in certs.SANCertificates.
+	SANDiffs() ([]*certs.SANDiff, error)
+}
+
+// Plan describes the changes of the renewal.
+type Plan struct {
+	// RemovedFiles is the files removed before the renewal
+	RemovedFiles []string
+	// RenewedFiles is the files written by the renewal
+	RenewedFiles []string
+	// Commands is the commands executed by the renewal
+	Commands [][]string
+}
+
+// Options holds the options used to create the Renewer.
+type Options struct {
+	Backend       string
+	KubernetesDir string
+	ConfigFile    string
+	// Config is the kubeadm config of the ConfigFile, the ConfigFile is read if it is nil. It is set by a dry run
+	// with the reconstructed kubeadm config, which is not written to the ConfigFile.
+	Config *kubeadm.Config
+	// RotateKeys generates new private keys instead of reusing the existing ones. NewRenewer sets it for the kubeadm
+	// backend, which always generates new private keys.
+	RotateKeys bool
+	// SANChanges is the SANs added to or removed from the certificates by the native backend.
+	SANChanges *certs.SANChanges
+	// Validity is the validity period of the certificates renewed by the native backend.
+	Validity *certs.Validity
+	// Names is the names of the targets to renew, all the targets are renewed if it is empty.
+	Names []string
+}
+
+// NewRenewer returns the Renewer of the backend.
+func NewRenewer(o *Options) (Renewer, error) {
+	if err := ValidateTargets(o.Names); err != nil {
+		return nil, err
+	}
+	if o.SANChanges != nil {
+		for _, name := range certs.SANCertificates {
+			if (len(o.SANChanges.Add[name]) > 0 || len(o.SANChanges.Remove[name]) > 0) && !certs.Selected(o.Names, name) {
+				return nil, errors.Errorf("the %s certificate is not renewed, can't change its SANs", name)
+			}
+		}
+	}
+
+	switch o.Backend {
+	case NativeBackend:
+		return &nativeRenewer{
+			kubernetesDir: o.KubernetesDir,
+			renewOptions: &certs.RenewOptions{
+				RotateKeys: o.RotateKeys,
+				SANChanges: o.SANChanges,
+				Validity:   o.Validity,
+				Names:      o.Names,
+			},
+		}, nil
+	case KubeadmBackend:
+		if o.ConfigFile == "" {
+			return nil, errors.Errorf("the %s backend requires the kubeadm config file, please use '--config'", KubeadmBackend)
+		}
+		if !o.RotateKeys {
+			// kubeadm can't reuse the private keys, so the options follow what it does.
+			klog.Warningf("[renewal] the %s backend always generates new private keys, rotate the keys", KubeadmBackend)
+			o.RotateKeys = true
+		}
+		if o.Validity != nil {
+			return nil, errors.Errorf("the %s backend doesn't support the validity period, please use the %s backend", KubeadmBackend, NativeBackend)
+		}
+		r := &kubeadmRenewer{kubernetesDir: o.KubernetesDir, configFile: o.ConfigFile, config: o.Config, names: o.Names}
+		if len(o.Names) > 0 {
+			if err := r.validateNames(); err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	}
+	return nil, errors.Errorf("unknown renewal backend %q, supported backends: %s|%s", o.Backend, NativeBackend, KubeadmBackend)
+}
+
+// nativeRenewer re-signs the existing certificates with the CA in the PKI directory.
+type nativeRenewer struct {
+	kubernetesDir string
+	renewOptions  *certs.RenewOptions
+}
+
+func (r *nativeRenewer) RenewCertificates() error {
+	return certs.RenewLeafCertificates(filepath.Join(r.kubernetesDir, "pki"), r.renewOptions)
+}
+
+func (r *nativeRenewer) RenewKubeConfigs() error {
+	return kubeconfig.RenewKubeConfigFiles(r.kubernetesDir, filepath.Join(r.kubernetesDir, "pki"), r.renewOptions)
+}
+
+func (r *nativeRenewer) Plan() (*Plan, error) {
+	certificatesDir := filepath.Join(r.kubernetesDir, "pki")
+	kubeconfigFiles, err := kubeconfig.ListKubeConfigFiles(r.kubernetesDir)
+	if err != nil {
+		return nil, err
+	}
+	renewedFiles, err := certs.LeafCertificateFiles(certificatesDir, r.renewOptions)
+	if err != nil {
+		return nil, err
+	}
+	for _, kf := range kubeconfigFiles {
+		if certs.Selected(r.renewOptions.Names, filepath.Base(kf)) {
+			renewedFiles = append(renewedFiles, kf)
+		}
+	}
+
+	return &Plan{
+		RemovedFiles: []string{},
+		RenewedFiles: renewedFiles,
+		Commands:     [][]string{},
+	}, nil
+}
+
+func (r *nativeRenewer) SANDiffs() ([]*certs.SANDiff, error) {
+	certificatesDir := filepath.Join(r.kubernetesDir, "pki")
+	diffs := []*certs.SANDiff{}
+	for _, name := range certs.SANCertificates {
+		if !certs.Selected(r.renewOptions.Names, name) {
+			continue
+		}
+		sans, err := certs.LoadCertificateSANs(certificatesDir, name)
+		if err != nil {
+			return nil, err
+		}
+		if sans == nil {
+			continue
+		}
+		diffs = append(diffs, &certs.SANDiff{Name: name, Old: sans, New: r.renewOptions.SANChanges.Apply(name, sans)})
+	}
+	return diffs, nil
+}
+
+// kubeadmRenewer recreates the certificates by kubeadm. If the kubeadm version supports `kubeadm certs renew`,
+// the certificates are renewed one by one in place, otherwise the old certificates are removed and
+// recreated by `kubeadm init phase`, which can't renew the certificates individually, and the client
+// certificates of the kubeconfig files are re-issued in-process.
+type kubeadmRenewer struct {
+	kubernetesDir string
+	configFile    string
+	// config is the kubeadm config of the configFile, the configFile is read if it is nil
+	config *kubeadm.Config
+	names  []string
+}
+
+// kubeadmKubeConfigFiles is the list of the kubeconfig files renewed by `kubeadm certs renew`,
+// kubelet.conf is excluded because the kubelet rotates its client certificate itself.
+var kubeadmKubeConfigFiles = []string{
+	"admin.conf",
+	"controller-manager.conf",
+	"scheduler.conf",
+}
+
+func (r *kubeadmRenewer) RenewCertificates() error {
+	supported, err := r.renewCertSupported()
+	if err != nil {
+		return err
+	}
+	if supported {
+		return r.renewEach(r.selected(certificateNames()))
+	}
+
+	klog.Info("[renewal] Remove old Kubernetes certificates exclude CA and sa")
+	if err := certs.RemoveOldCertificates(filepath.Join(r.kubernetesDir, "pki")); err != nil {
+		return err
+	}
+	return certs.RenewCertificate(r.configFile)
+}
+
+func (r *kubeadmRenewer) RenewKubeConfigs() error {
+	supported, err := r.renewCertSupported()
+	if err != nil {
+		return err
+	}
+	if supported {
+		klog.Info("[renewal] Skip kubelet.conf, the kubelet rotates its client certificate itself")
+		return r.renewEach(r.selected(kubeadmKubeConfigFiles))
+	}
+
+	// `kubeadm init phase kubeconfig` recreates the kubeconfig files from the config, which loses the hand-edited
+	// servers and cluster names, so the client certificates are re-issued in-process instead.
+	klog.Info("[renewal] Renew the client certificates of the kubeconfig files in-process")
+	return kubeconfig.RenewKubeConfigFiles(r.kubernetesDir, filepath.Join(r.kubernetesDir, "pki"), &certs.RenewOptions{RotateKeys: true})
+}
+
+func (r *kubeadmRenewer) Plan() (*Plan, error) {
+	supported, err := r.renewCertSupported()
+	if err != nil {
+		return nil, err
+	}
+	if supported {
+		return r.renewEachPlan()
+	}
+
+	removedFiles, err := certs.OldCertificates(filepath.Join(r.kubernetesDir, "pki"))
+	if err != nil {
+		return nil, err
+	}
+	kubeconfigFiles, err := kubeconfig.ListKubeConfigFiles(r.kubernetesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	certsCommand, err := kubeadm.PhasesCreateCertsCommand(r.configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Plan{
+		RemovedFiles: removedFiles,
+		RenewedFiles: append(append([]string{}, removedFiles...), kubeconfigFiles...),
+		Commands:     [][]string{certsCommand},
+	}, nil
+}
+
+// SANDiffs returns the SANs kubeadm signs the certificates with according to the config file,
+// `kubeadm certs renew` preserves the SANs of the existing certificates.
+func (r *kubeadmRenewer) SANDiffs() ([]*certs.SANDiff, error) {
+	supported, err := r.renewCertSupported()
+	if err != nil {
+		return nil, err
+	}
+	cfg := r.config
+	if cfg == nil {
+		cfg, err = kubeadm.FetchConfigurationFromConfigFile(r.configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	certificatesDir := filepath.Join(r.kubernetesDir, "pki")
+	diffs := []*certs.SANDiff{}
+	for _, name := range r.selected(certs.SANCertificates) {
+		sans, err := certs.LoadCertificateSANs(certificatesDir, name)
+		if err != nil {
+			return nil, err
+		}
+		if sans == nil || (cfg.ExternalEtcd != nil && name != "apiserver") {
+			continue
+		}
+
+		d := &certs.SANDiff{Name: name, Old: sans, New: sans}
+		if !supported {
+			d.New = config.ExpectedSANs(cfg, name)
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// validateNames returns an error if the targets can't be renewed individually by the installed kubeadm.
+func (r *kubeadmRenewer) validateNames() error {
+	supported, err := r.renewCertSupported()
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return errors.Errorf("the installed kubeadm can't renew the certificates individually, please use the %s backend", NativeBackend)
+	}
+	for _, name := range r.names {
+		if name == "kubelet.conf" {
+			return errors.Errorf("kubeadm can't renew kubelet.conf, please use the %s backend", NativeBackend)
+		}
+	}
+	return nil
+}
+
+// selected returns the names selected by the renewer.
+func (r *kubeadmRenewer) selected(names []string) []string {
+	result := []string{}
+	for _, name := range names {
+		if certs.Selected(r.names, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// renewCertSupported returns true if the installed kubeadm can renew a single certificate.
+func (r *kubeadmRenewer) renewCertSupported() (bool, error) {
+	args, err := kubeadm.RenewCertCommand(r.configFile, "apiserver")
+	if err != nil {
+		return false, err
+	}
+	return args != nil, nil
+}
+
+// renewEach renews the certificates and kubeconfig files one by one by `kubeadm certs renew`.
+func (r *kubeadmRenewer) renewEach(names []string) error {
+	for _, name := range names {
+		out, err := kubeadm.RenewCert(r.configFile, name)
+		klog.Info(string(out))
+		if err != nil {
+			return errors.Wrapf(err, "failed to renew %s by kubeadm", name)
+		}
+	}
+	return nil
+}
+
+func (r *kubeadmRenewer) renewEachPlan() (*Plan, error) {
+	certificatesDir := filepath.Join(r.kubernetesDir, "pki")
+	renewedFiles := []string{}
+	for _, c := range certs.LeafCertificates() {
+		if !certs.Selected(r.names, c.Name) {
+			continue
+		}
+		renewedFiles = append(renewedFiles, filepath.Join(certificatesDir, c.BaseName+".crt"), filepath.Join(certificatesDir, c.BaseName+".key"))
+	}
+	for _, kf := range r.selected(kubeadmKubeConfigFiles) {
+		renewedFiles = append(renewedFiles, filepath.Join(r.kubernetesDir, kf))
+	}
+
+	commands := [][]string{}
+	for _, name := range r.selected(append(certificateNames(), kubeadmKubeConfigFiles...)) {
+		command, err := kubeadm.RenewCertCommand(r.configFile, name)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+	}
+
+	return &Plan{
+		RemovedFiles: []string{},
+		RenewedFiles: renewedFiles,
+		Commands:     commands,
+	}, nil
+}
+
+// certificateNames returns the names of the leaf certificates used by `kubeadm certs renew`.
+func certificateNames() []string {
+	names := []string{}
+	for _, c := range certs.LeafCertificates() {
+		names = append(names, c.Name)
+	}
+	return names
+}
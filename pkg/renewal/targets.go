@@ -0,0 +1,153 @@
+package renewal
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/constants"
+	"github.com/pytimer/certadm/pkg/kubeconfig"
+	"github.com/pytimer/certadm/pkg/util/pkiutil"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+// KubeletService is the name of the kubelet service in the consumers of the targets.
+const KubeletService = "kubelet"
+
+// Target is a certificate or kubeconfig file which can be renewed individually.
+type Target struct {
+	// Name is the name used by `certadm renew <name>`, e.g. apiserver or admin.conf
+	Name string
+	// File is the path of the certificate or kubeconfig file relative to the Kubernetes directory,
+	// or the absolute path of the kubelet serving certificate
+	File string
+	// Consumers is the control plane components and the kubelet service which load the target,
+	// they are restarted after the target is renewed.
+	Consumers []string
+}
+
+// targetConsumers is the consumers of the targets by the name. The etcd healthcheck client certificate is
+// read by the etcd liveness probe every time, and admin.conf is only used by kubectl, so they have no consumer.
+var targetConsumers = map[string][]string{
+	"apiserver":                {"kube-apiserver"},
+	"apiserver-kubelet-client": {"kube-apiserver"},
+	"front-proxy-client":       {"kube-apiserver"},
+	"apiserver-etcd-client":    {"kube-apiserver"},
+	"etcd-server":              {"etcd"},
+	"etcd-peer":                {"etcd"},
+	"controller-manager.conf":  {"kube-controller-manager"},
+	"scheduler.conf":           {"kube-scheduler"},
+	"kubelet.conf":             {KubeletService},
+	certs.KubeletServingName:   {KubeletService},
+}
+
+// Targets returns the certificates and kubeconfig files which can be renewed individually.
+func Targets() []*Target {
+	targets := []*Target{}
+	for _, c := range certs.LeafCertificates() {
+		targets = append(targets, &Target{
+			Name:      c.Name,
+			File:      filepath.Join("pki", c.BaseName+".crt"),
+			Consumers: targetConsumers[c.Name],
+		})
+	}
+	for _, kf := range kubeconfig.KubeConfigFiles {
+		targets = append(targets, &Target{Name: kf, File: kf, Consumers: targetConsumers[kf]})
+	}
+	targets = append(targets, &Target{
+		Name:      certs.KubeletServingName,
+		File:      certs.KubeletServingCertificatePath(constants.KubeletCertificatesPath),
+		Consumers: targetConsumers[certs.KubeletServingName],
+	})
+	return targets
+}
+
+// TargetNames returns the names of the targets.
+func TargetNames() []string {
+	names := []string{}
+	for _, t := range Targets() {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// ValidateTargets returns an error if any of the names is not a target.
+func ValidateTargets(names []string) error {
+	unknown := sets.NewString(names...).Difference(sets.NewString(TargetNames()...))
+	if unknown.Len() > 0 {
+		return errors.Errorf("unknown certificates %v, see 'certadm renew --list' for the renewable certificates: %s", unknown.List(), strings.Join(TargetNames(), "|"))
+	}
+	return nil
+}
+
+// Consumers returns the consumers of the targets in the names, or of all the targets if the names is empty.
+func Consumers(names []string) []string {
+	consumers := []string{}
+	seen := sets.NewString()
+	for _, t := range Targets() {
+		if !certs.Selected(names, t.Name) {
+			continue
+		}
+		for _, c := range t.Consumers {
+			if seen.Has(c) {
+				continue
+			}
+			seen.Insert(c)
+			consumers = append(consumers, c)
+		}
+	}
+	return consumers
+}
+
+// LoadCertificate returns the certificate of the target in the kubernetesDir, or the client certificate embedded
+// in the kubeconfig file. It returns nil if the file not exists or the kubeconfig file does not embed the
+// client certificate.
+func (t *Target) LoadCertificate(kubernetesDir string) (*x509.Certificate, error) {
+	p := t.File
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(kubernetesDir, t.File)
+	}
+	if exists, err := path.Exists(path.CheckFollowSymlink, p); err != nil || !exists {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(t.File, ".crt") {
+		return kubeconfig.LoadClientCertificate(p)
+	}
+	cs, err := pkiutil.CertsFromFile(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the %s certificate", t.Name)
+	}
+	return cs[0], nil
+}
+
+// ExpiringTargets returns the names of the targets in the names, or of all the targets if the names is empty,
+// which expire within the duration. The targets which not exist are skipped.
+func ExpiringTargets(kubernetesDir string, within time.Duration, names []string) ([]string, error) {
+	deadline := time.Now().Add(within)
+	expiring := []string{}
+	for _, t := range Targets() {
+		if !certs.Selected(names, t.Name) {
+			continue
+		}
+		cert, err := t.LoadCertificate(kubernetesDir)
+		if err != nil {
+			return nil, err
+		}
+		if cert == nil {
+			klog.V(1).Infof("[renewal] %s has no certificate, skip it", t.Name)
+			continue
+		}
+		if cert.NotAfter.Before(deadline) {
+			klog.Infof("[renewal] %s expires on %s, within %s", t.Name, cert.NotAfter, within)
+			expiring = append(expiring, t.Name)
+		}
+	}
+	return expiring, nil
+}
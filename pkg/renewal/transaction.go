@@ -0,0 +1,159 @@
+package renewal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pytimer/certadm/pkg/certs"
+	"github.com/pytimer/certadm/pkg/kubeconfig"
+
+	"github.com/otiai10/copy"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+	"k8s.io/utils/path"
+)
+
+const stagingDirPrefix = ".certadm-staging-"
+
+// Run renews the certificates and kubeconfig files as a transaction. The native backend renews them
+// in a staging directory, validates them and renames them into the Kubernetes directory, so the
+// Kubernetes directory is untouched when the renewal fails. The kubeadm backend writes the fixed paths,
+// so it renews them in place and validates the result.
+func Run(o *Options) error {
+	if o.Backend == KubeadmBackend {
+		renewer, err := NewRenewer(o)
+		if err != nil {
+			return err
+		}
+		if err := renew(renewer); err != nil {
+			return err
+		}
+		return Validate(o.KubernetesDir, o.Names)
+	}
+
+	stagingDir, err := stage(o.KubernetesDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to stage the certificates")
+	}
+	defer os.RemoveAll(stagingDir)
+	klog.V(1).Infof("[renewal] Renew the certificates in the staging directory %s", stagingDir)
+
+	stagingOpts := *o
+	stagingOpts.KubernetesDir = stagingDir
+	renewer, err := NewRenewer(&stagingOpts)
+	if err != nil {
+		return err
+	}
+	if err := renew(renewer); err != nil {
+		return err
+	}
+
+	if err := Validate(stagingDir, o.Names); err != nil {
+		return errors.Wrap(err, "failed to validate the renewed certificates")
+	}
+
+	return commit(stagingDir, o.KubernetesDir)
+}
+
+// Validate checks the certificates and kubeconfig files in the names, or all of them if the names is empty,
+// in the kubernetesDir are valid.
+func Validate(kubernetesDir string, names []string) error {
+	certificatesDir := filepath.Join(kubernetesDir, "pki")
+	if err := certs.ValidateLeafCertificates(certificatesDir, names); err != nil {
+		return err
+	}
+	return kubeconfig.ValidateKubeConfigFiles(kubernetesDir, certificatesDir, names)
+}
+
+func renew(renewer Renewer) error {
+	if err := renewer.RenewCertificates(); err != nil {
+		return err
+	}
+	return renewer.RenewKubeConfigs()
+}
+
+// stage copies the PKI directory and the kubeconfig files into a staging directory in the kubernetesDir,
+// so every file can be renamed into the kubernetesDir atomically.
+func stage(kubernetesDir string) (string, error) {
+	stagingDir, err := ioutil.TempDir(kubernetesDir, stagingDirPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	if err := copy.Copy(filepath.Join(kubernetesDir, "pki"), filepath.Join(stagingDir, "pki")); err != nil {
+		os.RemoveAll(stagingDir)
+		return "", err
+	}
+	for _, kf := range kubeconfig.KubeConfigFiles {
+		src := filepath.Join(kubernetesDir, kf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, src); err != nil {
+			os.RemoveAll(stagingDir)
+			return "", err
+		} else if !exists {
+			continue
+		}
+		if err := copy.Copy(src, filepath.Join(stagingDir, kf)); err != nil {
+			os.RemoveAll(stagingDir)
+			return "", err
+		}
+	}
+	return stagingDir, nil
+}
+
+// commit renames the changed files of the PKI directory and the kubeconfig files from the staging directory
+// into the kubernetesDir one by one. The PKI directory itself is kept, because the running static pods bind-mount
+// it and its subdirectories by inode, and the unchanged files are not touched. The caller restores the backup
+// if it fails halfway.
+func commit(stagingDir, kubernetesDir string) error {
+	stagingCertificatesDir := filepath.Join(stagingDir, "pki")
+	certificatesDir := filepath.Join(kubernetesDir, "pki")
+	err := filepath.Walk(stagingCertificatesDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(stagingCertificatesDir, p)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(certificatesDir, rel)
+		if info.IsDir() {
+			return os.MkdirAll(dst, info.Mode().Perm())
+		}
+		return commitFile(p, dst)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, kf := range kubeconfig.KubeConfigFiles {
+		src := filepath.Join(stagingDir, kf)
+		if exists, err := path.Exists(path.CheckFollowSymlink, src); err != nil {
+			return err
+		} else if !exists {
+			continue
+		}
+		if err := commitFile(src, filepath.Join(kubernetesDir, kf)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commitFile renames the src over the dst if their contents differ.
+func commitFile(src, dst string) error {
+	srcData, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	dstData, err := ioutil.ReadFile(dst)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil && bytes.Equal(srcData, dstData) {
+		return nil
+	}
+	klog.V(1).Infof("[renewal] Commit %s", dst)
+	return os.Rename(src, dst)
+}
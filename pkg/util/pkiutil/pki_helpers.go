@@ -0,0 +1,411 @@
+package pkiutil
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"path/filepath"
+	"time"
+
+	"github.com/pytimer/certadm/pkg/util"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CertificateBlockType is a possible value for pem.Block.Type.
+	CertificateBlockType = "CERTIFICATE"
+	// RSAPrivateKeyBlockType is a possible value for pem.Block.Type.
+	RSAPrivateKeyBlockType = "RSA PRIVATE KEY"
+	// ECPrivateKeyBlockType is a possible value for pem.Block.Type.
+	ECPrivateKeyBlockType = "EC PRIVATE KEY"
+	// PrivateKeyBlockType is a possible value for pem.Block.Type.
+	PrivateKeyBlockType = "PRIVATE KEY"
+	// PublicKeyBlockType is a possible value for pem.Block.Type.
+	PublicKeyBlockType = "PUBLIC KEY"
+
+	rsaKeySize = 2048
+)
+
+// ParseCertsPEM returns the x509.Certificates contained in the given PEM-encoded byte array
+func ParseCertsPEM(pemCerts []byte) ([]*x509.Certificate, error) {
+	ok := false
+	certs := []*x509.Certificate{}
+	for len(pemCerts) > 0 {
+		var block *pem.Block
+		block, pemCerts = pem.Decode(pemCerts)
+		if block == nil {
+			break
+		}
+		// Only use PEM "CERTIFICATE" blocks without extra headers
+		if block.Type != CertificateBlockType || len(block.Headers) != 0 {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return certs, err
+		}
+
+		certs = append(certs, cert)
+		ok = true
+	}
+
+	if !ok {
+		return certs, errors.New("data does not contain any valid RSA or ECDSA certificates")
+	}
+	return certs, nil
+}
+
+// CertsFromFile returns the x509.Certificates contained in the given PEM-encoded file.
+func CertsFromFile(file string) ([]*x509.Certificate, error) {
+	pemBlock, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := ParseCertsPEM(pemBlock)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", file)
+	}
+	return certs, nil
+}
+
+// TryLoadCertFromDisk tries to load the cert from the disk
+func TryLoadCertFromDisk(pkiPath, name string) (*x509.Certificate, error) {
+	certificatePath := pathForCert(pkiPath, name)
+
+	certs, err := CertsFromFile(certificatePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load the certificate file %s", certificatePath)
+	}
+
+	// We are only putting one certificate in the certificate pem file, so it's safe to just pick the first one.
+	// The CA certificate file is a trust bundle during the CA rotation, the first one matches the CA key.
+	return certs[0], nil
+}
+
+// TryLoadCertsFromDisk tries to load all the certs in the cert file from the disk
+func TryLoadCertsFromDisk(pkiPath, name string) ([]*x509.Certificate, error) {
+	certificatePath := pathForCert(pkiPath, name)
+
+	certs, err := CertsFromFile(certificatePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load the certificate file %s", certificatePath)
+	}
+	return certs, nil
+}
+
+// TryLoadKeyFromDisk tries to load the key from the disk
+func TryLoadKeyFromDisk(pkiPath, name string) (crypto.Signer, error) {
+	privateKeyPath := pathForKey(pkiPath, name)
+
+	b, err := ioutil.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load the private key file %s", privateKeyPath)
+	}
+	key, err := ParsePrivateKeyPEM(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load the private key file %s", privateKeyPath)
+	}
+	return key, nil
+}
+
+// TryLoadCertAndKeyFromDisk tries to load a cert and a key from the disk
+func TryLoadCertAndKeyFromDisk(pkiPath, name string) (*x509.Certificate, crypto.Signer, error) {
+	cert, err := TryLoadCertFromDisk(pkiPath, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := TryLoadKeyFromDisk(pkiPath, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cert, key, nil
+}
+
+// ParsePrivateKeyPEM returns a private key parsed from a PEM block in the supplied data.
+// Recognizes PEM blocks for "EC PRIVATE KEY", "RSA PRIVATE KEY", or "PRIVATE KEY"
+func ParsePrivateKeyPEM(keyData []byte) (crypto.Signer, error) {
+	for len(keyData) > 0 {
+		var block *pem.Block
+		block, keyData = pem.Decode(keyData)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case ECPrivateKeyBlockType:
+			return x509.ParseECPrivateKey(block.Bytes)
+		case RSAPrivateKeyBlockType:
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case PrivateKeyBlockType:
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, errors.New("private key is not a crypto.Signer")
+			}
+			return signer, nil
+		}
+	}
+	return nil, errors.New("data does not contain a valid RSA or ECDSA private key")
+}
+
+// NewPrivateKey creates an RSA private key
+func NewPrivateKey() (crypto.Signer, error) {
+	return rsa.GenerateKey(rand.Reader, rsaKeySize)
+}
+
+// RenewSignedCert returns a copy of the cert signed by the CA with the new validity period,
+// the subject, SANs, key usages and extended key usages are preserved.
+// The NotAfter is capped at the NotAfter of the CA.
+func RenewSignedCert(cert *x509.Certificate, key crypto.Signer, caCert *x509.Certificate, caKey crypto.Signer, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	notAfter := now.Add(validity)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		RawSubject:            cert.RawSubject,
+		Subject:               cert.Subject,
+		DNSNames:              cert.DNSNames,
+		IPAddresses:           cert.IPAddresses,
+		EmailAddresses:        cert.EmailAddresses,
+		URIs:                  cert.URIs,
+		NotBefore:             now,
+		NotAfter:              notAfter,
+		KeyUsage:              cert.KeyUsage,
+		ExtKeyUsage:           cert.ExtKeyUsage,
+		UnknownExtKeyUsage:    cert.UnknownExtKeyUsage,
+		BasicConstraintsValid: cert.BasicConstraintsValid,
+		IsCA:                  cert.IsCA,
+	}
+	certDERBytes, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, key.Public(), caKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(certDERBytes)
+}
+
+// NewSelfSignedCACert creates a CA certificate with the subject of the existing CA certificate
+func NewSelfSignedCACert(cert *x509.Certificate, key crypto.Signer, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		RawSubject:            cert.RawSubject,
+		Subject:               cert.Subject,
+		NotBefore:             now,
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certDERBytes, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(certDERBytes)
+}
+
+// NewSelfSignedCert creates a self-signed certificate with the subject, SANs, key usages and extended key usages
+// of the cert, e.g. the self-signed serving certificate of the kubelet.
+func NewSelfSignedCert(cert *x509.Certificate, key crypto.Signer, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               cert.Subject,
+		DNSNames:              cert.DNSNames,
+		IPAddresses:           cert.IPAddresses,
+		NotBefore:             now,
+		NotAfter:              now.Add(validity),
+		KeyUsage:              cert.KeyUsage,
+		ExtKeyUsage:           cert.ExtKeyUsage,
+		BasicConstraintsValid: true,
+	}
+	certDERBytes, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(certDERBytes)
+}
+
+// VerifyCertAndKey checks the certificate matches the private key, is signed by the CA and is in the validity period.
+func VerifyCertAndKey(cert *x509.Certificate, key crypto.Signer, caCert *x509.Certificate) error {
+	certPublicKey, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(certPublicKey, publicKey) {
+		return errors.Errorf("the certificate %s does not match the private key", cert.Subject.CommonName)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	opts := x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if _, err := cert.Verify(opts); err != nil {
+		return errors.Wrapf(err, "failed to verify the certificate %s", cert.Subject.CommonName)
+	}
+	return nil
+}
+
+// EncodeCertPEM returns PEM-endcoded certificate data
+func EncodeCertPEM(cert *x509.Certificate) []byte {
+	block := pem.Block{
+		Type:  CertificateBlockType,
+		Bytes: cert.Raw,
+	}
+	return pem.EncodeToMemory(&block)
+}
+
+// EncodeCertsPEM returns PEM-encoded certificates data
+func EncodeCertsPEM(certs []*x509.Certificate) []byte {
+	b := []byte{}
+	for _, cert := range certs {
+		b = append(b, EncodeCertPEM(cert)...)
+	}
+	return b
+}
+
+// MarshalPrivateKeyToPEM converts a known private key type of RSA or ECDSA to
+// a PEM encoded block or returns an error.
+func MarshalPrivateKeyToPEM(privateKey crypto.PrivateKey) ([]byte, error) {
+	switch t := privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		derBytes, err := x509.MarshalECPrivateKey(t)
+		if err != nil {
+			return nil, err
+		}
+		block := &pem.Block{
+			Type:  ECPrivateKeyBlockType,
+			Bytes: derBytes,
+		}
+		return pem.EncodeToMemory(block), nil
+	case *rsa.PrivateKey:
+		block := &pem.Block{
+			Type:  RSAPrivateKeyBlockType,
+			Bytes: x509.MarshalPKCS1PrivateKey(t),
+		}
+		return pem.EncodeToMemory(block), nil
+	default:
+		return nil, errors.Errorf("private key is not a recognized type: %T", privateKey)
+	}
+}
+
+// WriteCert stores the given certificate at the given location
+func WriteCert(pkiPath, name string, cert *x509.Certificate) error {
+	if cert == nil {
+		return errors.New("certificate cannot be nil when writing to file")
+	}
+
+	certificatePath := pathForCert(pkiPath, name)
+	if err := util.WriteFileAtomic(certificatePath, EncodeCertPEM(cert), 0644); err != nil {
+		return errors.Wrapf(err, "unable to write certificate to file %s", certificatePath)
+	}
+	return nil
+}
+
+// WriteCerts stores the given certificates in one file at the given location
+func WriteCerts(pkiPath, name string, certs []*x509.Certificate) error {
+	if len(certs) == 0 {
+		return errors.New("certificates cannot be empty when writing to file")
+	}
+
+	certificatePath := pathForCert(pkiPath, name)
+	if err := util.WriteFileAtomic(certificatePath, EncodeCertsPEM(certs), 0644); err != nil {
+		return errors.Wrapf(err, "unable to write certificates to file %s", certificatePath)
+	}
+	return nil
+}
+
+// WriteKey stores the given key at the given location
+func WriteKey(pkiPath, name string, key crypto.Signer) error {
+	if key == nil {
+		return errors.New("private key cannot be nil when writing to file")
+	}
+
+	privateKeyPath := pathForKey(pkiPath, name)
+	encoded, err := MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return errors.Wrapf(err, "unable to marshal private key to PEM")
+	}
+	if err := util.WriteFileAtomic(privateKeyPath, encoded, 0600); err != nil {
+		return errors.Wrapf(err, "unable to write private key to file %s", privateKeyPath)
+	}
+	return nil
+}
+
+// WritePublicKey stores the given public key at the given location
+func WritePublicKey(pkiPath, name string, key crypto.PublicKey) error {
+	if key == nil {
+		return errors.New("public key cannot be nil when writing to file")
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return errors.Wrapf(err, "unable to marshal public key to PEM")
+	}
+	publicKeyPath := pathForPublicKey(pkiPath, name)
+	block := &pem.Block{
+		Type:  PublicKeyBlockType,
+		Bytes: der,
+	}
+	if err := util.WriteFileAtomic(publicKeyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		return errors.Wrapf(err, "unable to write public key to file %s", publicKeyPath)
+	}
+	return nil
+}
+
+// WriteCertAndKey stores certificate and key at the specified location
+func WriteCertAndKey(pkiPath, name string, cert *x509.Certificate, key crypto.Signer) error {
+	if err := WriteKey(pkiPath, name, key); err != nil {
+		return errors.Wrap(err, "couldn't write key")
+	}
+
+	return WriteCert(pkiPath, name, cert)
+}
+
+func pathForCert(pkiPath, name string) string {
+	return filepath.Join(pkiPath, name+".crt")
+}
+
+func pathForKey(pkiPath, name string) string {
+	return filepath.Join(pkiPath, name+".key")
+}
+
+func pathForPublicKey(pkiPath, name string) string {
+	return filepath.Join(pkiPath, name+".pub")
+}
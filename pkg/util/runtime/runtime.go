@@ -18,7 +18,7 @@ import (
 type ContainerRuntime interface {
 	IsDocker() bool
 	IsRunning() error
-	ListKubeContainers() ([]string, error)
+	ListKubeContainers(components ...string) ([]string, error)
 	RemoveContainers(containers []string) error
 	PullImage(image string) error
 	ImageExists(image string) (bool, error)
@@ -87,23 +87,38 @@ func (runtime *DockerRuntime) IsRunning() error {
 	return nil
 }
 
-// ListKubeContainers lists running k8s CRI pods
-func (runtime *CRIRuntime) ListKubeContainers() ([]string, error) {
-	out, err := runtime.exec.Command("crictl", "-r", runtime.criSocket, "pods", "--label", "tier=control-plane", "-q").CombinedOutput()
-	if err != nil {
-		return nil, errors.Wrapf(err, "output: %s, error", string(out))
+// ListKubeContainers lists running k8s CRI pods of the control plane components, or all the control plane pods
+// if the components is empty
+func (runtime *CRIRuntime) ListKubeContainers(components ...string) ([]string, error) {
+	labels := []string{"tier=control-plane"}
+	if len(components) > 0 {
+		labels = []string{}
+		for _, c := range components {
+			labels = append(labels, fmt.Sprintf("component=%s", c))
+		}
 	}
+
 	pods := []string{}
-	for _, pod := range strings.Fields(string(out)) {
-		pods = append(pods, pod)
+	for _, label := range labels {
+		out, err := runtime.exec.Command("crictl", "-r", runtime.criSocket, "pods", "--label", label, "-q").CombinedOutput()
+		if err != nil {
+			return nil, errors.Wrapf(err, "output: %s, error", string(out))
+		}
+		for _, pod := range strings.Fields(string(out)) {
+			pods = append(pods, pod)
+		}
 	}
 	return pods, nil
 }
 
-// ListKubeContainers lists running k8s containers
-func (runtime *DockerRuntime) ListKubeContainers() ([]string, error) {
+// ListKubeContainers lists running k8s containers of the control plane components, or all the control plane
+// containers if the components is empty
+func (runtime *DockerRuntime) ListKubeContainers(components ...string) ([]string, error) {
+	if len(components) == 0 {
+		components = constants.ControlPlaneNames
+	}
 	filterQuery := []string{"ps", "-a", "-q"}
-	for _, n := range constants.ControlPlaneNames {
+	for _, n := range components {
 		filterQuery = append(filterQuery, "--filter")
 		filterQuery = append(filterQuery, fmt.Sprintf("name=k8s_%s", n))
 	}
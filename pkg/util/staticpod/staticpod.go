@@ -0,0 +1,119 @@
+package staticpod
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pytimer/certadm/pkg/util"
+
+	"github.com/pkg/errors"
+)
+
+// ManifestPath returns the path of the static pod manifest of the component, e.g. kube-apiserver.
+func ManifestPath(manifestsDir, component string) string {
+	return filepath.Join(manifestsDir, component+".yaml")
+}
+
+// flagLineRegexp returns the regexp which matches the "- --<flag>=<value>" line of the container command,
+// the groups are the indent and the value.
+func flagLineRegexp(flag string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`^(\s*)- --%s=(.*?)\s*$`, regexp.QuoteMeta(flag)))
+}
+
+// GetFlagValues returns the values of the flag in the container command of the static pod manifest.
+func GetFlagValues(manifestPath, flag string) ([]string, error) {
+	lines, err := readLines(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	re := flagLineRegexp(flag)
+	values := []string{}
+	for _, line := range lines {
+		if m := re.FindStringSubmatch(line); m != nil {
+			values = append(values, unquote(m[2]))
+		}
+	}
+	return values, nil
+}
+
+// AppendFlag adds the flag with the value after the last occurrence of the same flag in the container command
+// of the static pod manifest, the other lines of the manifest are preserved. It does nothing if the flag with
+// the value exists already.
+func AppendFlag(manifestPath, flag, value string) error {
+	lines, err := readLines(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	re := flagLineRegexp(flag)
+	last := -1
+	indent := ""
+	for i, line := range lines {
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if unquote(m[2]) == value {
+			return nil
+		}
+		last, indent = i, m[1]
+	}
+	if last < 0 {
+		return errors.Errorf("the flag --%s not found in the static pod manifest %s", flag, manifestPath)
+	}
+
+	newLine := fmt.Sprintf("%s- --%s=%s", indent, flag, value)
+	lines = append(lines[:last+1], append([]string{newLine}, lines[last+1:]...)...)
+	return writeLines(manifestPath, lines)
+}
+
+// RemoveFlag removes the flag with the value from the container command of the static pod manifest,
+// it returns false if the flag with the value not exists.
+func RemoveFlag(manifestPath, flag, value string) (bool, error) {
+	lines, err := readLines(manifestPath)
+	if err != nil {
+		return false, err
+	}
+
+	re := flagLineRegexp(flag)
+	result := []string{}
+	removed := false
+	for _, line := range lines {
+		if m := re.FindStringSubmatch(line); m != nil && unquote(m[2]) == value {
+			removed = true
+			continue
+		}
+		result = append(result, line)
+	}
+	if !removed {
+		return false, nil
+	}
+	return true, writeLines(manifestPath, result)
+}
+
+func unquote(value string) string {
+	return strings.Trim(value, `"'`)
+}
+
+func readLines(manifestPath string) ([]string, error) {
+	b, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(b), "\n"), nil
+}
+
+// writeLines writes the manifest atomically with the permission of the existing file, the kubelet ignores
+// the temporary file because its name starts with a dot.
+func writeLines(manifestPath string, lines []string) error {
+	info, err := os.Stat(manifestPath)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(manifestPath, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+}
@@ -0,0 +1,4 @@
+package version
+
+// Version is the version of certadm, it is set by '-ldflags' when building.
+var Version = "unknown"